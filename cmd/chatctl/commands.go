@@ -0,0 +1,317 @@
+package main
+
+import (
+	"chats/internal/domain"
+	"chats/internal/export"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type command func(ctx context.Context, c *cli, args []string) error
+
+var commands = map[string]command{
+	"chats create":   createChat,
+	"chats list":     listChats,
+	"chats delete":   deleteChat,
+	"messages post":  postMessage,
+	"messages purge": purgeMessages,
+	"export":         exportChat,
+	"schema status":  schemaStatus,
+}
+
+// lookup finds the command named by the leading words of args and
+// returns the rest.
+func lookup(args []string) (command, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("%w: no command", errUsage)
+	}
+	if cmd, ok := commands[args[0]]; ok {
+		return cmd, args[1:], nil
+	}
+	if len(args) > 1 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd, args[2:], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: unknown command %q", errUsage, strings.Join(args[:min(len(args), 2)], " "))
+}
+
+func createChat(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: chats create takes a TITLE", errUsage)
+	}
+
+	chat, err := c.chats.CreateChat(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.render(chat, func(w io.Writer) {
+		printChats(w, []domain.Chat{*chat})
+	})
+}
+
+func listChats(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("chats list")
+	after := flags.Uint("after", 0, "list chats with IDs above this one")
+	limit := flags.Int("limit", 100, "list at most this many chats")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	chats, err := c.chatRepo.List(ctx, *after, *limit)
+	if err != nil {
+		return err
+	}
+	return c.render(chats, func(w io.Writer) {
+		printChats(w, chats)
+	})
+}
+
+func deleteChat(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("chats delete")
+	dryRun := flags.Bool("dry-run", false, "show the chat instead of deleting it")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	chat, err := c.chats.GetChatState(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		// Pinned to the version shown, so a chat changed meanwhile is kept.
+		if err := c.chats.DeleteChat(ctx, id, chat.Version); err != nil {
+			return err
+		}
+	}
+
+	result := deleteResult{DryRun: *dryRun, Chat: chat}
+	return c.render(result, func(w io.Writer) {
+		verb := "Deleted"
+		if *dryRun {
+			verb = "Would delete"
+		}
+		fmt.Fprintf(w, "%s chat %d %q and its messages up to seq %d.\n", verb, chat.ID, chat.Title, chat.LastSeq)
+	})
+}
+
+type deleteResult struct {
+	DryRun bool         `json:"dry_run"`
+	Chat   *domain.Chat `json:"chat"`
+}
+
+func postMessage(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("%w: messages post takes a CHAT_ID and a TEXT", errUsage)
+	}
+	chatID, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	message, err := c.messages.CreateMessage(ctx, chatID, args[1])
+	if err != nil {
+		return err
+	}
+	return c.render(message, func(w io.Writer) {
+		fmt.Fprintf(w, "Posted message %d to chat %d.\n", message.Seq, message.ChatID)
+	})
+}
+
+func purgeMessages(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("messages purge")
+	beforeFlag := flags.String("before", "", "delete messages created before this date (required)")
+	chatID := flags.Uint("chat", 0, "purge only this chat")
+	dryRun := flags.Bool("dry-run", false, "count the messages instead of deleting them")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *beforeFlag == "" {
+		return fmt.Errorf("%w: messages purge needs -before", errUsage)
+	}
+	before, err := parseTime(*beforeFlag)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if *dryRun {
+		count, err = c.messageRepo.CountCreatedBefore(ctx, *chatID, before)
+	} else {
+		count, err = c.messageRepo.DeleteCreatedBefore(ctx, *chatID, before)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := purgeResult{DryRun: *dryRun, Before: before, Messages: count}
+	if *chatID > 0 {
+		result.ChatID = chatID
+	}
+	return c.render(result, func(w io.Writer) {
+		verb := "Deleted"
+		if *dryRun {
+			verb = "Would delete"
+		}
+		scope := "all chats"
+		if *chatID > 0 {
+			scope = fmt.Sprintf("chat %d", *chatID)
+		}
+		fmt.Fprintf(w, "%s %d messages created before %s in %s.\n", verb, count, before.Format(time.RFC3339), scope)
+	})
+}
+
+type purgeResult struct {
+	DryRun   bool      `json:"dry_run"`
+	ChatID   *uint     `json:"chat_id,omitempty"`
+	Before   time.Time `json:"before"`
+	Messages int64     `json:"messages"`
+}
+
+// exportChat writes a transcript in any export format; -o does not apply.
+func exportChat(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("export")
+	formatName := flags.String("format", "json", "transcript format: "+strings.Join(export.Names(), ", "))
+	fromFlag := flags.String("from", "", "include messages created at or after this date")
+	toFlag := flags.String("to", "", "include messages created before this date")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	format, ok := export.Lookup(*formatName)
+	if !ok {
+		return fmt.Errorf("%w: format must be one of %s", errUsage, strings.Join(export.Names(), ", "))
+	}
+
+	var filter domain.HistoryFilter
+	if *fromFlag != "" {
+		if filter.From, err = parseTime(*fromFlag); err != nil {
+			return err
+		}
+	}
+	if *toFlag != "" {
+		if filter.To, err = parseTime(*toFlag); err != nil {
+			return err
+		}
+	}
+
+	chat, messages, err := c.exports.ExportChat(ctx, id, filter)
+	if err != nil {
+		return err
+	}
+
+	enc := format.New(c.out)
+	if err := enc.Begin(export.Header{Chat: chat, From: filter.From, To: filter.To, ExportedAt: time.Now()}); err != nil {
+		return err
+	}
+	for message, err := range messages {
+		if err != nil {
+			return err
+		}
+		if err := enc.Message(message); err != nil {
+			return err
+		}
+	}
+	return enc.End()
+}
+
+func schemaStatus(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: schema status takes no arguments", errUsage)
+	}
+
+	migrations, err := c.db.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	result := schemaResult{Current: true, Migrations: make([]migrationResult, 0, len(migrations))}
+	if err := c.db.CheckMigrations(ctx); err != nil {
+		result.Current = false
+		result.Error = err.Error()
+	}
+	for _, m := range migrations {
+		entry := migrationResult{Version: m.Version, Name: m.Name}
+		if !m.AppliedAt.IsZero() {
+			entry.AppliedAt = &m.AppliedAt
+		}
+		result.Migrations = append(result.Migrations, entry)
+	}
+
+	return c.render(result, func(w io.Writer) {
+		tw := newTable(w, "APPLIED AT", "MIGRATION")
+		for _, m := range result.Migrations {
+			appliedAt := "pending"
+			if m.AppliedAt != nil {
+				appliedAt = m.AppliedAt.Local().Format(time.DateTime)
+			}
+			tw.row(appliedAt, m.Name)
+		}
+		tw.flush()
+		if result.Current {
+			fmt.Fprintln(w, "\nSchema is current.")
+		} else {
+			fmt.Fprintf(w, "\nSchema is not current: %s.\n", result.Error)
+		}
+	})
+}
+
+type schemaResult struct {
+	Current    bool              `json:"current"`
+	Error      string            `json:"error,omitempty"`
+	Migrations []migrationResult `json:"migrations"`
+}
+
+type migrationResult struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// parse parses the command's flags and checks it got want arguments.
+func parse(flags *flag.FlagSet, args []string, want int) error {
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s: %v", errUsage, flags.Name(), err)
+	}
+	if flags.NArg() != want {
+		return fmt.Errorf("%w: %s takes %d arguments, got %d", errUsage, flags.Name(), want, flags.NArg())
+	}
+	return nil
+}
+
+func parseID(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 0)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: invalid ID %q", errUsage, s)
+	}
+	return uint(id), nil
+}
+
+// parseTime accepts a date, meaning its midnight UTC, or an RFC 3339 time.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q, want 2006-01-02 or RFC 3339", errUsage, s)
+	}
+	return t, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"chats/internal/domain"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	_, rest, err := lookup([]string{"chats", "delete", "-dry-run", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-dry-run", "3"}, rest)
+
+	_, rest, err = lookup([]string{"export", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3"}, rest)
+
+	_, _, err = lookup([]string{"chats", "rename"})
+	assert.ErrorIs(t, err, errUsage)
+
+	_, _, err = lookup(nil)
+	assert.ErrorIs(t, err, errUsage)
+}
+
+func TestParseTime(t *testing.T) {
+	day, err := parseTime("2026-01-31")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), day)
+
+	instant, err := parseTime("2026-01-31T10:00:00+03:00")
+	require.NoError(t, err)
+	assert.True(t, instant.Equal(time.Date(2026, 1, 31, 7, 0, 0, 0, time.UTC)))
+
+	_, err = parseTime("31.01.2026")
+	assert.ErrorIs(t, err, errUsage)
+}
+
+func TestRender(t *testing.T) {
+	chats := []domain.Chat{{ID: 7, Title: "general", LastSeq: 42}}
+
+	var buf bytes.Buffer
+	c := &cli{out: &buf, output: outputTable}
+	require.NoError(t, c.render(chats, func(w io.Writer) { printChats(w, chats) }))
+	assert.Contains(t, buf.String(), "ID  TITLE    LAST SEQ")
+	assert.Contains(t, buf.String(), "7   general  42")
+
+	buf.Reset()
+	c.output = outputJSON
+	require.NoError(t, c.render(chats, func(w io.Writer) { printChats(w, chats) }))
+	assert.Contains(t, buf.String(), `"title": "general"`)
+	assert.Contains(t, buf.String(), `"last_seq": 42`)
+}
@@ -0,0 +1,126 @@
+// Command chatctl runs operator tasks directly against the chat service's
+// database. It reads the server's config (CONFIG_PATH and environment
+// variables) and goes through the same services, so validation, limits
+// and outbox events behave as they do through the API.
+package main
+
+import (
+	"chats/internal/config"
+	"chats/internal/database"
+	"chats/internal/limits"
+	"chats/internal/logging"
+	"chats/internal/repositories"
+	"chats/internal/services"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const usage = `usage: chatctl [-o table|json] [-v] <command> [flags] [args]
+
+commands:
+  chats create TITLE                                   create a chat
+  chats list [-after ID] [-limit N]                    list chats by ID
+  chats delete [-dry-run] ID                           delete a chat with its messages
+  messages post CHAT_ID TEXT                           post a message
+  messages purge -before DATE [-chat ID] [-dry-run]    delete messages created before DATE
+  export [-format F] [-from DATE] [-to DATE] CHAT_ID   write a chat transcript to stdout
+  schema status                                        list migrations and check the schema
+
+DATE is 2006-01-02 or RFC 3339.`
+
+// errUsage reports a malformed command line.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	flags := flag.NewFlagSet("chatctl", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	output := flags.String("o", outputTable, "output format: table or json")
+	verbose := flags.Bool("v", false, "log at the configured level instead of warnings only")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	err := run(ctx, os.Stdout, *output, *verbose, flags.Args())
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "chatctl: %v\n\n%s\n", err, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chatctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, out io.Writer, output string, verbose bool, args []string) error {
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("%w: unknown output %q", errUsage, output)
+	}
+	cmd, args, err := lookup(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	level := slog.LevelWarn
+	if verbose {
+		if level, err = logging.ParseLevel(cfg.Log.Level); err != nil {
+			return err
+		}
+	}
+	slog.SetDefault(logging.New(os.Stderr, logging.Options{Env: cfg.ENV, Level: level, Redact: cfg.Log.Redact}))
+	limits.Set(cfg.Limits.Limits())
+
+	db, err := database.NewDatabase(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return cmd(ctx, newCLI(out, output, db), args)
+}
+
+// cli carries what commands need: the output and the same services and
+// repositories the server wires up.
+type cli struct {
+	out    io.Writer
+	output string
+
+	db          *database.Database
+	chatRepo    repositories.ChatRepository
+	messageRepo repositories.MessageRepository
+	chats       services.ChatService
+	messages    services.MessageService
+	exports     services.ExportService
+}
+
+func newCLI(out io.Writer, output string, db *database.Database) *cli {
+	chatRepo := repositories.NewChatRepository(db.DB)
+	messageRepo := repositories.NewMessageRepository(db.DB)
+	txManager := repositories.NewTxManager(db.DB)
+	outboxRepo := repositories.NewOutboxRepository(db.DB)
+	chatService := services.NewChatService(chatRepo, txManager, outboxRepo)
+
+	return &cli{
+		out:         out,
+		output:      output,
+		db:          db,
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+		chats:       chatService,
+		messages:    services.NewMessageService(messageRepo, chatService, txManager, outboxRepo),
+		exports:     services.NewExportService(chatRepo, messageRepo),
+	}
+}
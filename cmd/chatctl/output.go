@@ -0,0 +1,59 @@
+package main
+
+import (
+	"chats/internal/domain"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output modes selected with -o.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// render writes v as indented JSON, or as a table for people.
+func (c *cli) render(v any, table func(w io.Writer)) error {
+	if c.output == outputJSON {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	table(c.out)
+	return nil
+}
+
+type table struct {
+	w *tabwriter.Writer
+}
+
+func newTable(w io.Writer, columns ...string) *table {
+	t := &table{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}
+	t.row(columns...)
+	return t
+}
+
+func (t *table) row(cells ...string) {
+	fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+}
+
+func (t *table) flush() {
+	_ = t.w.Flush()
+}
+
+func printChats(w io.Writer, chats []domain.Chat) {
+	t := newTable(w, "ID", "TITLE", "LAST SEQ", "UPDATED AT")
+	for _, chat := range chats {
+		t.row(
+			fmt.Sprint(chat.ID),
+			chat.Title,
+			fmt.Sprint(chat.LastSeq),
+			chat.UpdatedAt.Local().Format(time.DateTime),
+		)
+	}
+	t.flush()
+}
@@ -1,38 +1,336 @@
 package main
 
 import (
+	"chats/internal/apiversion"
 	"chats/internal/config"
 	"chats/internal/database"
+	"chats/internal/events"
+	"chats/internal/graph"
+	"chats/internal/grpcserver"
 	"chats/internal/handlers"
+	"chats/internal/health"
+	"chats/internal/lifecycle"
+	"chats/internal/limits"
+	"chats/internal/logging"
+	"chats/internal/metrics"
+	"chats/internal/middleware"
+	"chats/internal/outbox"
+	"chats/internal/realtime"
 	"chats/internal/repositories"
 	"chats/internal/route"
 	"chats/internal/services"
-	"log"
+	"chats/internal/tracing"
+	"chats/internal/webhooks"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
 )
 
 func main() {
-	cfg := config.LoadConfig()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// After the first signal a second one kills the process right away.
+	context.AfterFunc(ctx, stop)
+
+	printConfig := flag.Bool("print-config", false, "print the effective config, with secrets masked, and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: server [flags]\n       server migrate <command>\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 && flag.Arg(0) != "migrate" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatal("Failed to load config", err)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fatal("Failed to print config", err)
+		}
+		return
+	}
 
-	db, err := database.NewDatabase(cfg.DB)
+	// The level and the limits change on SIGHUP, see reloadConfig.
+	logLevel := new(slog.LevelVar)
+	level, err := logging.ParseLevel(cfg.Log.Level)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Invalid log level", err)
 	}
+	logLevel.Set(level)
+	limits.Set(cfg.Limits.Limits())
+	// Also routes the log package, used by some dependencies, through slog.
+	slog.SetDefault(logging.New(os.Stderr, logging.Options{Env: cfg.ENV, Level: logLevel, Redact: cfg.Log.Redact}))
+
+	if flag.Arg(0) == "migrate" {
+		err := runMigrate(ctx, cfg, flag.Args()[1:])
+		if errors.Is(err, errMigrateUsage) {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			os.Exit(2)
+		}
+		if err != nil {
+			fatal("Migration failed", err)
+		}
+		return
+	}
+
+	db, err := database.NewDatabase(ctx, cfg.DB)
+	if err != nil {
+		fatal("Failed to connect to database", err)
+	}
+	switch cfg.DB.Migrations {
+	case "up":
+		if err := db.MigrateUp(ctx); err != nil {
+			fatal("Failed to run migrations", err)
+		}
+		slog.Info("Database migrated")
+	case "check":
+		if err := db.CheckMigrations(ctx); err != nil {
+			fatal("Database schema is not current, run `server migrate up`", err)
+		}
+	}
+
+	// Components stop in reverse order: readiness turns not-ready, the
+	// realtime hub ends streams, the servers drain, the workers stop and
+	// the database closes last.
+	app := lifecycle.New(cfg.Shutdown.Timeout)
+	app.OnStop("database", func(context.Context) error { return db.Close() })
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+	// Flushes the spans of drained requests before the database closes.
+	app.OnStop("tracing", shutdownTracing)
+	if err := db.DB.Use(tracing.GORMPlugin()); err != nil {
+		fatal("Failed to instrument database", err)
+	}
+
+	var appMetrics *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		appMetrics = metrics.New()
+		if err := db.DB.Use(appMetrics.GORMPlugin()); err != nil {
+			fatal("Failed to instrument database", err)
+		}
+		sqlDB, err := db.DB.DB()
+		if err != nil {
+			fatal("Failed to get sql.DB", err)
+		}
+		if err := appMetrics.RegisterDB(sqlDB, cfg.DB.DBName); err != nil {
+			fatal("Failed to register database metrics", err)
+		}
+		for addr, replicaDB := range db.Replicas() {
+			if err := appMetrics.RegisterDB(replicaDB, cfg.DB.DBName+"@"+addr); err != nil {
+				fatal("Failed to register database metrics", err)
+			}
+		}
+	}
+
+	readiness := health.NewChecker(cfg.Health.CheckTimeout, cfg.Health.CacheTTL)
+	readiness.Register("database", db.HealthCheck)
+	readiness.Register("migrations", db.CheckMigrations)
 
 	chatRepo := repositories.NewChatRepository(db.DB)
-	chatService := services.NewChatService(chatRepo)
+	txManager := repositories.NewTxManager(db.DB)
+	outboxRepo := repositories.NewOutboxRepository(db.DB)
+
+	webhookRepo := repositories.NewWebhookRepository(db.DB)
+	webhookDispatcher := webhooks.NewDispatcher(webhookRepo, cfg.Webhooks)
+	webhookService := services.NewWebhookService(webhookRepo, chatRepo, webhookDispatcher)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+	eventBus := events.NewBus()
+	eventBus.Handle(webhookService.Publish)
+	outboxRelay := outbox.NewRelay(outboxRepo, txManager, eventBus, cfg.Outbox)
+
+	chatService := tracing.ChatService(services.NewChatService(chatRepo, txManager, outboxRepo))
+	if appMetrics != nil {
+		chatService = appMetrics.ChatService(chatService)
+	}
 	chatHandler := handlers.NewChatHandler(chatService)
 
 	messageRepo := repositories.NewMessageRepository(db.DB)
-	messageService := services.NewMessageService(messageRepo, chatService)
+	messageService := tracing.MessageService(services.NewMessageService(messageRepo, chatService, txManager, outboxRepo))
+	if appMetrics != nil {
+		messageService = appMetrics.MessageService(messageService)
+	}
 	messageHandler := handlers.NewMessageHandler(messageService)
 
-	apiRoute := route.SetupQuestionRoutes(chatHandler, messageHandler)
+	importService := services.NewImportService(messageRepo, chatService, txManager, cfg.Import)
+	importHandler := handlers.NewImportHandler(importService)
+
+	exportService := services.NewExportService(chatRepo, messageRepo)
+	exportHandler := handlers.NewExportHandler(exportService)
+
+	batchHandler := handlers.NewBatchHandler(chatService, messageService, webhookService, txManager, handlers.BatchOptions{
+		MaxOperations:  cfg.Batch.MaxOperations,
+		AdminToken:     cfg.Auth.AdminToken,
+		RequireIfMatch: cfg.API.RequireIfMatch,
+	})
+
+	idempotencyRepo := repositories.NewIdempotencyRepository(db.DB)
+
+	hub := realtime.NewHub(messageService, cfg.Realtime.PollInterval)
+	eventBus.Handle(hub.Publish)
+
+	app.Go("webhook dispatcher", webhookDispatcher.Run)
+	app.Go("outbox relay", outboxRelay.Run)
+	app.Go("idempotency janitor", func(ctx context.Context) {
+		middleware.RunIdempotencyJanitor(ctx, idempotencyRepo, cfg.Idempotency.CleanupInterval)
+	})
+	app.Go("replica monitor", func(ctx context.Context) {
+		db.MonitorReplicas(ctx, cfg.DB.ReplicaCheckInterval)
+	})
+	app.Go("config reloader", func(ctx context.Context) {
+		reloadConfig(ctx, cfg, logLevel)
+	})
+
+	// Callers stay on the primary after a write through either API.
+	unary := []grpc.UnaryServerInterceptor{grpcserver.Deadline(cfg.DB.QueryTimeout)}
+	var readYourWrites func(http.Handler) http.Handler
+	if len(cfg.DB.Replicas) > 0 && cfg.DB.ReadYourWrites > 0 {
+		stickiness := middleware.NewStickiness(cfg.DB.ReadYourWrites)
+		readYourWrites = middleware.ReadYourWrites(stickiness)
+		unary = append(unary, grpcserver.ReadYourWrites(stickiness))
+	}
+
+	grpcServer, grpcHealth := grpcserver.NewServer(chatService, messageService, hub, grpc.ChainUnaryInterceptor(unary...))
+	grpcAddr := cfg.GRPC.Address + ":" + cfg.GRPC.Port
+	grpcListener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		fatal("gRPC server failed to listen", err)
+	}
+	slog.Info("gRPC server starting", "address", grpcAddr)
+	app.ServeGRPC("gRPC server", grpcServer, grpcHealth, grpcListener)
+
+	var compression func(http.Handler) http.Handler
+	if cfg.Compression.Enabled {
+		compression = middleware.Compress(cfg.Compression.MinSize)
+	}
+
+	var realIP func(http.Handler) http.Handler
+	if trusted, _ := cfg.Server.TrustedPrefixes(); len(trusted) > 0 {
+		realIP = middleware.RealIP(trusted)
+	}
+
+	var instrument func(http.Handler) http.Handler
+	var metricsHandler http.Handler
+	if appMetrics != nil {
+		instrument = appMetrics.Middleware
+		metricsHandler = appMetrics.Handler()
+	}
+
+	apiRoute := route.SetupQuestionRoutes(route.Deps{
+		ChatHandler:    chatHandler,
+		MessageHandler: messageHandler,
+		WebhookHandler: webhookHandler,
+		ImportHandler:  importHandler,
+		ExportHandler:  exportHandler,
+		BatchHandler:   batchHandler,
+		HealthHandler:  handlers.NewHealthHandler(readiness),
+		GraphQL:        graph.NewHandler(chatService, messageService, hub, cfg.GraphQL),
+		AdminToken:     cfg.Auth.AdminToken,
+		Deprecation:    apiversion.Deprecation{At: cfg.API.V1DeprecatedAt, Sunset: cfg.API.V1Sunset},
+		RequireIfMatch: cfg.API.RequireIfMatch,
+		MaxBodyBytes:   cfg.Server.MaxBodyBytes,
+		Idempotency:    middleware.Idempotency(idempotencyRepo, cfg.Idempotency.TTL, cfg.Idempotency.LockTimeout),
+		Compression:    compression,
+		ReadYourWrites: readYourWrites,
+		Deadline:       middleware.Deadline(cfg.DB.QueryTimeout),
+		RealIP:         realIP,
+		Tracing:        tracing.Middleware,
+		Metrics:        instrument,
+		MetricsHandler: metricsHandler,
+	})
 
 	serverAddr := cfg.Server.Address + ":" + cfg.Server.Port
-	log.Printf("Server starting on %s", serverAddr)
+	server := &http.Server{
+		Addr:              serverAddr,
+		Handler:           apiRoute,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	listener, err := net.Listen("tcp", serverAddr)
+	if err != nil {
+		fatal("Server failed to listen", err)
+	}
+	slog.Info("Server starting", "address", serverAddr)
+	app.ServeHTTP("HTTP server", server, listener)
+
+	app.OnStop("realtime hub", func(context.Context) error {
+		hub.Close()
+		return nil
+	})
+
+	// Stops first: report not-ready and keep serving until load balancers
+	// have noticed, then let the servers drain.
+	app.OnStop("readiness", func(ctx context.Context) error {
+		readiness.SetDraining()
+		grpcHealth.Shutdown()
+		select {
+		case <-time.After(cfg.Shutdown.DrainDelay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	if err := app.Run(ctx); err != nil {
+		fatal("Server stopped with errors", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// reloadConfig applies the log level and limits of the config reread on
+// every SIGHUP; an invalid config is ignored.
+func reloadConfig(ctx context.Context, cfg *config.Config, logLevel *slog.LevelVar) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
 
-	if err := http.ListenAndServe(serverAddr, apiRoute); err != nil {
-		log.Fatal("Server failed to start:", err)
+		next, err := config.LoadConfig()
+		if err != nil {
+			slog.Error("Config reload failed, keeping the current config", "error", err)
+			continue
+		}
+		level, err := logging.ParseLevel(next.Log.Level)
+		if err != nil {
+			slog.Error("Config reload failed, keeping the current config", "error", err)
+			continue
+		}
+
+		logLevel.Set(level)
+		limits.Set(next.Limits.Limits())
+		slog.Info("Config reloaded", "log_level", level, "limits", next.Limits)
+		if paths := cfg.RestartRequired(next); len(paths) > 0 {
+			slog.Warn("Config changes need a restart to take effect", "settings", paths)
+		}
 	}
 }
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
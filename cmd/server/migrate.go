@@ -0,0 +1,80 @@
+package main
+
+import (
+	"chats/internal/config"
+	"chats/internal/database"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up           apply all pending migrations
+  down         roll back the latest migration
+  redo         roll back the latest migration and apply it again
+  status       list migrations and when they were applied
+  create NAME  add an empty SQL migration to ` + database.MigrationsDir
+
+var errMigrateUsage = errors.New(migrateUsage)
+
+// runMigrate runs `server migrate <command>` against the configured database.
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		return database.CreateMigration(database.MigrationsDir, args[1])
+	}
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+
+	var run func(*database.Database) error
+	switch args[0] {
+	case "up":
+		run = func(db *database.Database) error { return db.MigrateUp(ctx) }
+	case "down":
+		run = func(db *database.Database) error { return db.MigrateDown(ctx) }
+	case "redo":
+		run = func(db *database.Database) error { return db.MigrateRedo(ctx) }
+	case "status":
+		run = func(db *database.Database) error { return printMigrationStatus(ctx, db) }
+	default:
+		return errMigrateUsage
+	}
+
+	db, err := database.NewDatabase(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return run(db)
+}
+
+func printMigrationStatus(ctx context.Context, db *database.Database) error {
+	migrations, err := db.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APPLIED AT\tMIGRATION")
+	for _, m := range migrations {
+		appliedAt := "pending"
+		if !m.AppliedAt.IsZero() {
+			appliedAt = m.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", appliedAt, m.Name)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE chats c
+SET updated_at = GREATEST(c.created_at, COALESCE((SELECT MAX(created_at) FROM messages WHERE chat_id = c.id), c.created_at));
+
+ALTER TABLE chats ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE chats ALTER COLUMN updated_at SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chats DROP COLUMN updated_at;
+-- +goose StatementEnd
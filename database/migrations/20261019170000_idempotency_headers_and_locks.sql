@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS response_headers JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+UPDATE idempotency_keys
+SET response_headers = jsonb_build_object('Content-Type', jsonb_build_array(content_type))
+WHERE content_type <> '';
+-- content_type stays for instances of the previous release during a rolling deploy.
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE idempotency_keys
+SET content_type = response_headers -> 'Content-Type' ->> 0
+WHERE response_headers ? 'Content-Type';
+ALTER TABLE idempotency_keys DROP COLUMN locked_until;
+ALTER TABLE idempotency_keys DROP COLUMN response_headers;
+-- +goose StatementEnd
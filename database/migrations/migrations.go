@@ -0,0 +1,10 @@
+// Package migrations embeds the goose SQL migrations into the binary, so
+// the server and its migrate command work from any directory.
+package migrations
+
+import "embed"
+
+// FS holds the migrations, named <version>_<description>.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,154 @@
+// Package apiversion resolves which REST API version a request targets and
+// marks deprecated versions in responses.
+package apiversion
+
+import (
+	"chats/internal/problem"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+
+	Latest  = V2
+	Default = V1
+)
+
+const (
+	Header = "Api-Version"
+
+	// vendorPrefix selects a version through Accept, e.g.
+	// "application/vnd.chats.v2+json". A version parameter on any media type,
+	// e.g. "application/json; version=2" or "application/msgpack; version=2",
+	// works too.
+	vendorPrefix = "application/vnd.chats.v"
+)
+
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+func (v Version) supported() bool {
+	return v >= V1 && v <= Latest
+}
+
+type contextKey struct{}
+
+func WithVersion(ctx context.Context, v Version) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the version resolved for the request, Default when
+// no versioning middleware ran.
+func FromContext(ctx context.Context) Version {
+	if v, ok := ctx.Value(contextKey{}).(Version); ok {
+		return v
+	}
+	return Default
+}
+
+// Use pins the version for routes mounted under an explicit prefix such as
+// /api/v2.
+func Use(v Version) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(Header, v.String())
+			next.ServeHTTP(w, r.WithContext(WithVersion(r.Context(), v)))
+		})
+	}
+}
+
+// Negotiate picks the version from the Accept header for unversioned routes,
+// falling back to Default. Asking for an unknown version is answered with 406.
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		v, ok, err := fromAccept(r.Header.Values("Accept"))
+		if err != nil {
+			problem.Write(w, r, problem.New(http.StatusNotAcceptable, problem.CodeNotAcceptable, err.Error()))
+			return
+		}
+		if !ok {
+			v = Default
+		}
+
+		w.Header().Set(Header, v.String())
+		next.ServeHTTP(w, r.WithContext(WithVersion(r.Context(), v)))
+	})
+}
+
+func fromAccept(values []string) (Version, bool, error) {
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+
+			var raw string
+			switch {
+			case strings.HasPrefix(mediaType, vendorPrefix) && strings.HasSuffix(mediaType, "+json"):
+				raw = strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorPrefix), "+json")
+			case params["version"] != "":
+				raw = strings.TrimPrefix(params["version"], "v")
+			default:
+				continue
+			}
+
+			n, err := strconv.Atoi(raw)
+			if v := Version(n); err == nil && v.supported() {
+				return v, true, nil
+			}
+			return 0, false, fmt.Errorf("unsupported API version %q, supported versions are v1 to %s", raw, Latest)
+		}
+	}
+	return 0, false, nil
+}
+
+// Deprecation describes when a version was deprecated and when it stops
+// being served. Zero times are omitted from the headers.
+type Deprecation struct {
+	At     time.Time
+	Sunset time.Time
+}
+
+// Deprecate adds Deprecation (RFC 9745), Sunset (RFC 8594) and a
+// successor-version link to responses served for version v. It must run
+// after Use or Negotiate.
+func Deprecate(v Version, d Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d.At.IsZero() && d.Sunset.IsZero() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if FromContext(r.Context()) == v {
+				if !d.At.IsZero() {
+					w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.At.Unix(), 10))
+				}
+				if !d.Sunset.IsZero() {
+					w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+				}
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor(r.URL.Path)))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// successor maps /api/... and /api/v1/... to the same path under /api/v2.
+func successor(path string) string {
+	rest := strings.TrimPrefix(path, "/api")
+	rest = strings.TrimPrefix(rest, "/"+V1.String())
+	return "/api/" + Latest.String() + rest
+}
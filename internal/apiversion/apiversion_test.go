@@ -0,0 +1,67 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	var got Version
+	h := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		accept     string
+		want       Version
+		wantStatus int
+	}{
+		{"", V1, http.StatusOK},
+		{"application/json", V1, http.StatusOK},
+		{"application/vnd.chats.v2+json", V2, http.StatusOK},
+		{"text/html, application/json; version=2", V2, http.StatusOK},
+		{"application/msgpack; version=2", V2, http.StatusOK},
+		{"application/vnd.chats.v1+json", V1, http.StatusOK},
+		{"application/vnd.chats.v9+json", 0, http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		got = 0
+		req := httptest.NewRequest(http.MethodGet, "/api/chats/1", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rr := httptest.NewRecorder()
+
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, tt.wantStatus, rr.Code, tt.accept)
+		assert.Equal(t, tt.want, got, tt.accept)
+		assert.Contains(t, rr.Header().Values("Vary"), "Accept")
+	}
+}
+
+func TestDeprecate(t *testing.T) {
+	d := Deprecation{
+		At:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC),
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	rr := httptest.NewRecorder()
+	Use(V1)(Deprecate(V1, d)(ok)).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/chats/1/messages", nil))
+	assert.Equal(t, "@1792368000", rr.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 19 Apr 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/chats/1/messages>; rel="successor-version"`, rr.Header().Get("Link"))
+	assert.Equal(t, "v1", rr.Header().Get(Header))
+
+	rr = httptest.NewRecorder()
+	Use(V2)(Deprecate(V1, d)(ok)).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v2/chats/1", nil))
+	assert.Empty(t, rr.Header().Get("Deprecation"))
+	assert.Empty(t, rr.Header().Get("Sunset"))
+	assert.Equal(t, "v2", rr.Header().Get(Header))
+}
@@ -0,0 +1,126 @@
+// Package codec encodes and decodes HTTP bodies as JSON, MessagePack or
+// Protobuf and picks the format from Accept and Content-Type.
+package codec
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned when a value has no representation in a
+// format, e.g. a type without a protobuf message.
+var ErrUnsupported = errors.New("codec: value cannot be represented in this format")
+
+type Codec interface {
+	// Name is a short token for the format, such as "json".
+	Name() string
+	// ContentType is sent in Content-Type; MediaTypes are what Accept and
+	// Content-Type may name to select the codec.
+	ContentType() string
+	MediaTypes() []string
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+var (
+	JSON        Codec = jsonCodec{}
+	MessagePack Codec = msgpackCodec{}
+	Protobuf    Codec = protobufCodec{}
+)
+
+// all is ordered by server preference, used to break ties in Accept.
+var all = []Codec{JSON, MessagePack, Protobuf}
+
+// byMediaType finds the codec for a media type without parameters.
+// Structured +json types such as application/vnd.chats.v2+json are JSON.
+func byMediaType(mediaType string) (Codec, bool) {
+	for _, c := range all {
+		if slices.Contains(c.MediaTypes(), mediaType) {
+			return c, true
+		}
+	}
+	if strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json") {
+		return JSON, true
+	}
+	return nil, false
+}
+
+// ForContentType returns the codec of a request body. A missing
+// Content-Type is read as JSON, which is what clients have always sent.
+func ForContentType(header string) (Codec, bool) {
+	if strings.TrimSpace(header) == "" {
+		return JSON, true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil, false
+	}
+	return byMediaType(mediaType)
+}
+
+// Negotiate returns the codecs the Accept header allows, most preferred
+// first. Wildcards match every codec in server order; an empty or missing
+// header accepts anything. Callers fall back to JSON when the list is
+// empty rather than answering 406.
+func Negotiate(accept string) []Codec {
+	if strings.TrimSpace(accept) == "" {
+		return all
+	}
+
+	type candidate struct {
+		codec Codec
+		q     float64
+	}
+
+	// Media types named explicitly win over wildcards, whatever the order.
+	explicit := map[Codec]float64{}
+	var candidates []candidate
+	wildcard := 0.0
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		if c, ok := byMediaType(mediaType); ok {
+			if _, seen := explicit[c]; !seen {
+				explicit[c] = q
+				candidates = append(candidates, candidate{c, q})
+			}
+		} else if mediaType == "*/*" || mediaType == "application/*" {
+			wildcard = max(wildcard, q)
+		}
+	}
+	for _, c := range all {
+		if _, seen := explicit[c]; !seen {
+			candidates = append(candidates, candidate{c, wildcard})
+		}
+	}
+	candidates = slices.DeleteFunc(candidates, func(c candidate) bool { return c.q <= 0 })
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+
+	codecs := make([]Codec, 0, len(candidates))
+	for _, c := range candidates {
+		codecs = append(codecs, c.codec)
+	}
+	return codecs
+}
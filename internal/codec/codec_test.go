@@ -0,0 +1,81 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	chatsv1 "chats/api/chats/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   []Codec
+	}{
+		{"", all},
+		{"*/*", all},
+		{"application/json", []Codec{JSON}},
+		{"application/vnd.chats.v2+json", []Codec{JSON}},
+		{"application/msgpack", []Codec{MessagePack}},
+		{"application/x-protobuf, application/json;q=0.5", []Codec{Protobuf, JSON}},
+		{"application/json;q=0.5, application/x-msgpack", []Codec{MessagePack, JSON}},
+		{"application/*;q=0.1, application/x-protobuf", []Codec{Protobuf, JSON, MessagePack}},
+		{"*/*, application/json;q=0", []Codec{MessagePack, Protobuf}},
+		{"text/html", []Codec{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(tt.accept))
+		})
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	for header, want := range map[string]Codec{
+		"":                                JSON,
+		"application/json; charset=utf-8": JSON,
+		"application/msgpack":             MessagePack,
+		"application/x-protobuf":          Protobuf,
+	} {
+		got, ok := ForContentType(header)
+		assert.True(t, ok, header)
+		assert.Equal(t, want, got, header)
+	}
+
+	_, ok := ForContentType("text/plain")
+	assert.False(t, ok)
+}
+
+func TestMessagePack_UsesJSONNames(t *testing.T) {
+	type embedded struct {
+		ID uint `json:"id"`
+	}
+	type value struct {
+		*embedded
+		Title    string     `json:"title"`
+		EditedAt *time.Time `json:"edited_at,omitempty"`
+		Secret   string     `json:"-"`
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, MessagePack.Encode(&buf, value{embedded: &embedded{ID: 7}, Title: "Чат", Secret: "s"}))
+
+	var decoded map[string]any
+	require.NoError(t, MessagePack.Decode(bytes.NewReader(buf.Bytes()), &decoded))
+	assert.Equal(t, map[string]any{"id": uint64(7), "title": "Чат"}, decoded)
+}
+
+func TestProtobuf(t *testing.T) {
+	var buf bytes.Buffer
+	assert.ErrorIs(t, Protobuf.Encode(&buf, map[string]string{}), ErrUnsupported)
+
+	require.NoError(t, Protobuf.Encode(&buf, &chatsv1.CreateChatRequest{Title: "Чат"}))
+	var decoded chatsv1.CreateChatRequest
+	require.NoError(t, Protobuf.Decode(&buf, &decoded))
+	assert.Equal(t, "Чат", decoded.GetTitle())
+}
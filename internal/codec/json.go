@@ -0,0 +1,22 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) MediaTypes() []string { return []string{"application/json"} }
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
@@ -0,0 +1,27 @@
+package codec
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+// msgpackCodec reuses the json struct tags, so field names match the JSON
+// representation. Times use the MessagePack timestamp extension.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	return msgpack.NewEncoder(w).UseJSONTag(true).Encode(v)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	return msgpack.NewDecoder(r).UseJSONTag(true).Decode(v)
+}
@@ -0,0 +1,44 @@
+package codec
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// protobufCodec only handles proto.Message values; callers convert their
+// types to the chats.v1 messages first.
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string { return "protobuf" }
+
+func (protobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protobufCodec) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf"}
+}
+
+func (protobufCodec) Encode(w io.Writer, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrUnsupported
+	}
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (protobufCodec) Decode(r io.Reader, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrUnsupported
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, m)
+}
@@ -1,50 +1,316 @@
 package config
 
 import (
-	"log"
+	"chats/internal/limits"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// Every field can be set in the YAML file and overridden by its environment
+// variable, such as DB_PASSWORD for database.password. Secrets can also be
+// read from a file named by DB_PASSWORD_FILE and the like.
 type Config struct {
-	ENV    string         `yaml:"env" env-default:"development"`
-	DB     DatabaseConfig `yaml:"database"`
-	Server HttpServer     `yaml:"http_server"`
+	ENV         string            `yaml:"env" env:"ENV" env-default:"development"`
+	Log         LogConfig         `yaml:"log" env-prefix:"LOG_"`
+	Limits      LimitsConfig      `yaml:"limits" env-prefix:"LIMITS_"`
+	DB          DatabaseConfig    `yaml:"database" env-prefix:"DB_"`
+	Server      HttpServer        `yaml:"http_server" env-prefix:"HTTP_"`
+	GRPC        GRPCServer        `yaml:"grpc_server" env-prefix:"GRPC_"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Webhooks    WebhookConfig     `yaml:"webhooks" env-prefix:"WEBHOOKS_"`
+	Outbox      OutboxConfig      `yaml:"outbox" env-prefix:"OUTBOX_"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" env-prefix:"IDEMPOTENCY_"`
+	Realtime    RealtimeConfig    `yaml:"realtime" env-prefix:"REALTIME_"`
+	GraphQL     GraphQLConfig     `yaml:"graphql" env-prefix:"GRAPHQL_"`
+	API         APIConfig         `yaml:"api" env-prefix:"API_"`
+	Import      ImportConfig      `yaml:"import" env-prefix:"IMPORT_"`
+	Batch       BatchConfig       `yaml:"batch" env-prefix:"BATCH_"`
+	Compression CompressionConfig `yaml:"compression" env-prefix:"COMPRESSION_"`
+	Shutdown    ShutdownConfig    `yaml:"shutdown" env-prefix:"SHUTDOWN_"`
+	Health      HealthConfig      `yaml:"health" env-prefix:"HEALTH_"`
+	Metrics     MetricsConfig     `yaml:"metrics" env-prefix:"METRICS_"`
+	Tracing     TracingConfig     `yaml:"tracing" env-prefix:"TRACING_"`
 }
 
 type HttpServer struct {
-	Address string `yaml:"address" default:"localhost"`
-	Port    string `yaml:"port" default:"8080"`
+	Address string `yaml:"address" env:"ADDRESS" env-default:"localhost"`
+	Port    string `yaml:"port" env:"PORT" env-default:"8080"`
+	// ReadHeaderTimeout and ReadTimeout bound reading a request, WriteTimeout
+	// writing its response; IdleTimeout closes idle keep-alive connections.
+	// Imports and exports lift the read and write deadlines for themselves.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"READ_HEADER_TIMEOUT" env-default:"5s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT" env-default:"30s"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT" env-default:"60s"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT" env-default:"2m"`
+	// MaxBodyBytes caps JSON request bodies; imports stream and are not capped.
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"MAX_BODY_BYTES" env-default:"1048576"`
+	// TrustedProxies are the addresses or CIDR ranges of load balancers
+	// whose X-Forwarded-For is believed when identifying the client.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+}
+
+// TrustedPrefixes parses TrustedProxies, a bare address standing for itself.
+func (c HttpServer) TrustedPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, entry := range c.TrustedProxies {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an address nor a CIDR range", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+type GRPCServer struct {
+	Address string `yaml:"address" env:"ADDRESS" env-default:"0.0.0.0"`
+	Port    string `yaml:"port" env:"PORT" env-default:"9090"`
 }
 
 type DatabaseConfig struct {
-	Host     string `yaml:"host" default:"localhost"`
-	Port     string `yaml:"port" default:"5432"`
-	DBName   string `yaml:"dbname" default:"postgres"`
-	User     string `yaml:"user" default:"root"`
-	Password string `yaml:"password" default:""`
-	SSLMode  string `yaml:"sslmode" env-default:"disable"`
-}
-
-func LoadConfig() *Config {
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		log.Fatal("CONFIG_PATH environment variable not set")
-		//configPath = "./config/config.yaml"
+	Host     string `yaml:"host" env:"HOST" env-default:"localhost"`
+	Port     string `yaml:"port" env:"PORT" env-default:"5432"`
+	DBName   string `yaml:"dbname" env:"NAME" env-default:"postgres"`
+	User     string `yaml:"user" env:"USER" env-default:"root"`
+	Password string `yaml:"password" env:"PASSWORD" secret:"true"`
+	SSLMode  string `yaml:"sslmode" env:"SSLMODE" env-default:"disable"`
+	// Migrations is up to apply pending migrations when the server starts,
+	// or check to refuse to start unless the schema is current, for
+	// deployments that run the migrate command as a separate step.
+	Migrations string `yaml:"migrations" env:"MIGRATIONS" env-default:"up"`
+	// ConnectTimeout is how long startup keeps retrying an unreachable database.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"CONNECT_TIMEOUT" env-default:"1m"`
+	// LogLevel is silent, error, warn (slow queries) or info (every
+	// statement, logged at debug level).
+	LogLevel           string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"warn"`
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"SLOW_QUERY_THRESHOLD" env-default:"200ms"`
+	// LogParams renders query parameters, which include message texts,
+	// into logged SQL.
+	LogParams bool `yaml:"log_params" env:"LOG_PARAMS" env-default:"false"`
+
+	// Pool settings apply to each pool, the primary's and every replica's.
+	// Zero lifetimes keep connections open indefinitely.
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"MAX_OPEN_CONNS" env-default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"MAX_IDLE_CONNS" env-default:"10"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"CONN_MAX_IDLE_TIME"`
+	// StatementTimeout is Postgres' statement_timeout for the server's
+	// sessions; migrations run without it. QueryTimeout is the deadline of
+	// one HTTP request or unary gRPC call, shared by all its statements and
+	// their waits for a free connection; exports, imports and subscriptions
+	// are exempt. Zero turns either off.
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"STATEMENT_TIMEOUT"`
+	QueryTimeout     time.Duration `yaml:"query_timeout" env:"QUERY_TIMEOUT"`
+
+	// Replicas are host or host:port addresses of read replicas, reached
+	// with the primary's credentials. Reads outside transactions are spread
+	// over the healthy ones; writes and transactions stay on the primary.
+	Replicas []string `yaml:"replicas" env:"REPLICAS" env-separator:","`
+	// ReplicaCheckInterval is how often replicas are checked. One that
+	// fails, or lags more than ReplicaMaxLag (zero: no limit), is out of
+	// rotation until a later check passes.
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" env:"REPLICA_CHECK_INTERVAL" env-default:"5s"`
+	ReplicaMaxLag        time.Duration `yaml:"replica_max_lag" env:"REPLICA_MAX_LAG"`
+	// ReadYourWrites keeps a caller's reads, over HTTP or unary gRPC, on the
+	// primary for this long after it writes, so it sees its own changes
+	// despite replication lag; zero turns it off.
+	ReadYourWrites time.Duration `yaml:"read_your_writes" env:"READ_YOUR_WRITES"`
+}
+
+// ReplicaAddress splits a Replicas entry into host and port, the port
+// defaulting to the primary's.
+func (c DatabaseConfig) ReplicaAddress(entry string) (host, port string) {
+	if host, port, err := net.SplitHostPort(entry); err == nil {
+		return host, port
 	}
+	return entry, c.Port
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error. The format follows ENV: text
+	// for local and development, JSON otherwise. Reloaded on SIGHUP.
+	Level string `yaml:"level" env:"LEVEL" env-default:"info"`
+	// Redact hides sensitive values, such as message texts, in logs.
+	// Defaults to true.
+	Redact bool `yaml:"redact" env:"REDACT"`
+}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatalf("congig file does not exist: %s", configPath)
+// LimitsConfig bounds request contents. Reloaded on SIGHUP.
+type LimitsConfig struct {
+	MaxMessageLength int `yaml:"max_message_length" env:"MAX_MESSAGE_LENGTH" env-default:"5000"`
+	MaxTitleLength   int `yaml:"max_title_length" env:"MAX_TITLE_LENGTH" env-default:"200"`
+	// DefaultHistory and MaxHistory bound the messages returned by history
+	// reads when the limit parameter is missing and at most.
+	DefaultHistory int `yaml:"default_history" env:"DEFAULT_HISTORY" env-default:"20"`
+	MaxHistory     int `yaml:"max_history" env:"MAX_HISTORY" env-default:"100"`
+}
+
+func (c LimitsConfig) Limits() limits.Limits {
+	return limits.Limits{
+		MaxMessageLength: c.MaxMessageLength,
+		MaxTitleLength:   c.MaxTitleLength,
+		DefaultHistory:   c.DefaultHistory,
+		MaxHistory:       c.MaxHistory,
 	}
+}
+
+type AuthConfig struct {
+	// AdminToken protects /api/admin routes. Admin routes are disabled when empty.
+	AdminToken string `yaml:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+}
+
+type WebhookConfig struct {
+	Workers              int           `yaml:"workers" env:"WORKERS" env-default:"4"`
+	PollInterval         time.Duration `yaml:"poll_interval" env:"POLL_INTERVAL" env-default:"1s"`
+	Timeout              time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"10s"`
+	MaxAttempts          int           `yaml:"max_attempts" env:"MAX_ATTEMPTS" env-default:"6"`
+	BaseBackoff          time.Duration `yaml:"base_backoff" env:"BASE_BACKOFF" env-default:"2s"`
+	MaxBackoff           time.Duration `yaml:"max_backoff" env:"MAX_BACKOFF" env-default:"10m"`
+	DisableAfterFailures int           `yaml:"disable_after_failures" env:"DISABLE_AFTER_FAILURES" env-default:"20"`
+	AllowPrivateNetworks bool          `yaml:"allow_private_networks" env:"ALLOW_PRIVATE_NETWORKS" env-default:"false"`
+}
+
+type OutboxConfig struct {
+	PollInterval    time.Duration `yaml:"poll_interval" env:"POLL_INTERVAL" env-default:"500ms"`
+	BatchSize       int           `yaml:"batch_size" env:"BATCH_SIZE" env-default:"100"`
+	Retention       time.Duration `yaml:"retention" env:"RETENTION" env-default:"168h"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"CLEANUP_INTERVAL" env-default:"1h"`
+}
+
+type IdempotencyConfig struct {
+	TTL             time.Duration `yaml:"ttl" env:"TTL" env-default:"24h"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"CLEANUP_INTERVAL" env-default:"1h"`
+	// LockTimeout is how long a request holds its key; a claim left by a
+	// request that crashed can be taken over after it.
+	LockTimeout time.Duration `yaml:"lock_timeout" env:"LOCK_TIMEOUT" env-default:"2m"`
+}
+
+type RealtimeConfig struct {
+	// PollInterval bounds the delay for messages created on other replicas.
+	PollInterval time.Duration `yaml:"poll_interval" env:"POLL_INTERVAL" env-default:"2s"`
+}
+
+type GraphQLConfig struct {
+	// MaxDepth and MaxComplexity bound the cost of a single operation.
+	MaxDepth      int `yaml:"max_depth" env:"MAX_DEPTH" env-default:"8"`
+	MaxComplexity int `yaml:"max_complexity" env:"MAX_COMPLEXITY" env-default:"1000"`
+}
+
+type APIConfig struct {
+	// V1DeprecatedAt and V1Sunset are announced in the Deprecation and
+	// Sunset headers of v1 responses; unset dates are not announced.
+	V1DeprecatedAt time.Time `yaml:"v1_deprecated_at,omitempty" env:"V1_DEPRECATED_AT" env-layout:"2006-01-02"`
+	V1Sunset       time.Time `yaml:"v1_sunset,omitempty" env:"V1_SUNSET" env-layout:"2006-01-02"`
+	// RequireIfMatch rejects PATCH and DELETE without If-Match with 428.
+	RequireIfMatch bool `yaml:"require_if_match" env:"REQUIRE_IF_MATCH" env-default:"false"`
+}
+
+type ImportConfig struct {
+	// BatchSize is the number of messages committed per transaction.
+	BatchSize int `yaml:"batch_size" env:"BATCH_SIZE" env-default:"1000"`
+	// MaxErrors caps the per-line errors listed in an import report.
+	MaxErrors int `yaml:"max_errors" env:"MAX_ERRORS" env-default:"1000"`
+}
+
+type BatchConfig struct {
+	// MaxOperations caps the operations of one POST /api/batch request.
+	MaxOperations int `yaml:"max_operations" env:"MAX_OPERATIONS" env-default:"50"`
+}
+
+type CompressionConfig struct {
+	// Enabled defaults to true.
+	Enabled bool `yaml:"enabled" env:"ENABLED"`
+	// MinSize is the smallest response body worth compressing, in bytes.
+	MinSize int `yaml:"min_size" env:"MIN_SIZE" env-default:"1024"`
+}
+
+type ShutdownConfig struct {
+	// Timeout bounds the whole shutdown: draining requests and streams,
+	// stopping workers and closing the database.
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"30s"`
+	// DrainDelay is how long the server keeps serving after it starts
+	// reporting not-ready, so load balancers can take it out of rotation.
+	DrainDelay time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY" env-default:"5s"`
+}
+
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check.
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"CHECK_TIMEOUT" env-default:"2s"`
+	// CacheTTL is how long a readiness report is reused between probes.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"CACHE_TTL" env-default:"1s"`
+}
+
+type MetricsConfig struct {
+	// Enabled serves Prometheus metrics at /metrics. Defaults to true.
+	Enabled bool `yaml:"enabled" env:"ENABLED"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp (OTLP over HTTP).
+	Exporter     string `yaml:"exporter" env:"EXPORTER" env-default:"none"`
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"OTLP_ENDPOINT" env-default:"localhost:4318"`
+	// OTLPInsecure defaults to true, for a collector on localhost.
+	OTLPInsecure bool `yaml:"otlp_insecure" env:"OTLP_INSECURE"`
+	// SampleRatio is the share of new traces recorded; requests that
+	// arrive with a trace context follow the caller's decision. Defaults to 1.
+	SampleRatio float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" env:"SERVICE_NAME" env-default:"chats"`
+}
+
+// defaults sets the fields whose zero value is a valid setting, which
+// env-default would override even when set to false or 0 explicitly.
+func defaults() Config {
+	var c Config
+	c.Log.Redact = true
+	c.Compression.Enabled = true
+	c.Metrics.Enabled = true
+	c.Tracing.OTLPInsecure = true
+	c.Tracing.SampleRatio = 1
+	c.DB.ConnMaxLifetime = 30 * time.Minute
+	c.DB.ConnMaxIdleTime = 5 * time.Minute
+	c.DB.StatementTimeout = 30 * time.Second
+	c.DB.QueryTimeout = 5 * time.Second
+	c.DB.ReplicaMaxLag = 10 * time.Second
+	c.DB.ReadYourWrites = 5 * time.Second
+	return c
+}
 
-	var config Config
+// LoadConfig reads the YAML file named by CONFIG_PATH, if set, applies
+// environment overrides and _FILE secrets, and validates the result.
+// Without CONFIG_PATH the config comes from the environment alone.
+func LoadConfig() (*Config, error) {
+	config := defaults()
 
-	if err := cleanenv.ReadConfig(configPath, &config); err != nil {
-		log.Fatalf("cannot read config: %s", err)
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("cannot read config file: %w", err)
+		}
+		if err := cleanenv.ReadConfig(path, &config); err != nil {
+			return nil, fmt.Errorf("cannot read config %s: %w", path, err)
+		}
+	} else if err := cleanenv.ReadEnv(&config); err != nil {
+		return nil, fmt.Errorf("cannot read config from environment: %w", err)
 	}
 
-	log.Printf("Config loaded: DB Host=%s, Port=%s", config.DB.Host, config.DB.Port)
+	if err := readSecretFiles(&config); err != nil {
+		return nil, err
+	}
 
-	return &config
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
 }
+
+// ErrInvalid wraps validation failures.
+var ErrInvalid = errors.New("invalid config")
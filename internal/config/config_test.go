@@ -0,0 +1,166 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "localhost", cfg.Server.Address)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, "5432", cfg.DB.Port)
+	assert.Equal(t, "root", cfg.DB.User)
+	assert.Equal(t, 5000, cfg.Limits.MaxMessageLength)
+	assert.Equal(t, 100, cfg.Limits.MaxHistory)
+	assert.True(t, cfg.Log.Redact)
+	assert.True(t, cfg.Metrics.Enabled)
+	assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+	assert.Equal(t, 25, cfg.DB.MaxOpenConns)
+	assert.Equal(t, 30*time.Second, cfg.DB.StatementTimeout)
+	assert.Equal(t, 5*time.Second, cfg.DB.QueryTimeout)
+	assert.Empty(t, cfg.DB.Replicas)
+	assert.Equal(t, 5*time.Second, cfg.DB.ReadYourWrites)
+}
+
+func TestLoadConfig_FileAndEnvironment(t *testing.T) {
+	t.Setenv("CONFIG_PATH", writeFile(t, "config.yaml", `
+log:
+  redact: false
+database:
+  host: db
+  password: from-file
+limits:
+  max_message_length: 1000
+metrics:
+  enabled: false
+tracing:
+  sample_ratio: 0
+`))
+	t.Setenv("DB_HOST", "replica")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "90s")
+	t.Setenv("LIMITS_MAX_HISTORY", "50")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "replica", cfg.DB.Host, "the environment overrides the file")
+	assert.Equal(t, "from-file", cfg.DB.Password)
+	assert.Equal(t, 90*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(t, 1000, cfg.Limits.MaxMessageLength)
+	assert.Equal(t, 50, cfg.Limits.MaxHistory)
+	assert.False(t, cfg.Log.Redact, "false in the file is not replaced by the default")
+	assert.False(t, cfg.Metrics.Enabled)
+	assert.Zero(t, cfg.Tracing.SampleRatio)
+}
+
+func TestLoadConfig_Replicas(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("DB_REPLICAS", "replica-1,replica-2:5433")
+	t.Setenv("DB_READ_YOUR_WRITES", "0s")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"replica-1", "replica-2:5433"}, cfg.DB.Replicas)
+	host, port := cfg.DB.ReplicaAddress(cfg.DB.Replicas[0])
+	assert.Equal(t, "replica-1", host)
+	assert.Equal(t, "5432", port, "defaults to the primary's port")
+	host, port = cfg.DB.ReplicaAddress(cfg.DB.Replicas[1])
+	assert.Equal(t, "replica-2", host)
+	assert.Equal(t, "5433", port)
+	assert.Zero(t, cfg.DB.ReadYourWrites, "zero turns stickiness off")
+
+	t.Setenv("DB_REPLICAS", "replica-1:pg")
+	_, err = LoadConfig()
+	require.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorContains(t, err, "database.replicas:")
+}
+
+func TestLoadConfig_MissingFile(t *testing.T) {
+	t.Setenv("CONFIG_PATH", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, err := LoadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_SecretFiles(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("DB_PASSWORD", "from-env")
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "db_password", "s3cr3t\n"))
+	t.Setenv("ADMIN_TOKEN_FILE", writeFile(t, "admin_token", "t0k3n"))
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", cfg.DB.Password)
+	assert.Equal(t, "t0k3n", cfg.Auth.AdminToken)
+
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "DB_PASSWORD_FILE")
+}
+
+func TestValidate_ReportsEveryError(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("HTTP_PORT", "http")
+	t.Setenv("LIMITS_DEFAULT_HISTORY", "500")
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+	t.Setenv("DB_MAX_IDLE_CONNS", "50")
+
+	_, err := LoadConfig()
+	require.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorContains(t, err, "log.level:")
+	assert.ErrorContains(t, err, "http_server.port:")
+	assert.ErrorContains(t, err, "limits.default_history:")
+	assert.ErrorContains(t, err, "tracing.exporter:")
+	assert.ErrorContains(t, err, "database.max_idle_conns:")
+}
+
+func TestPrint_MasksSecrets(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("DB_PASSWORD", "s3cr3t")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+	assert.NotContains(t, buf.String(), "s3cr3t")
+	assert.Contains(t, buf.String(), "password: '"+Masked+"'")
+	assert.Contains(t, buf.String(), "admin_token: \"\"")
+	assert.Contains(t, buf.String(), "write_timeout: 1m0s")
+	assert.Equal(t, "s3cr3t", cfg.DB.Password, "printing leaves the config intact")
+}
+
+func TestRestartRequired(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	current, err := LoadConfig()
+	require.NoError(t, err)
+
+	next := *current
+	next.Log.Level = "debug"
+	next.Limits.MaxMessageLength = 100
+	assert.Empty(t, current.RestartRequired(&next))
+
+	next.DB.Host = "replica"
+	next.Server.WriteTimeout = time.Minute * 5
+	assert.Equal(t, []string{"database.host", "http_server.write_timeout"}, current.RestartRequired(&next))
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// reloadable reports whether a setting takes effect on SIGHUP.
+func reloadable(path string) bool {
+	return path == "log.level" || strings.HasPrefix(path, "limits.")
+}
+
+// RestartRequired lists the settings that differ in next but only take
+// effect after a restart.
+func (c *Config) RestartRequired(next *Config) []string {
+	current := settings(reflect.ValueOf(c))
+	changed := settings(reflect.ValueOf(next))
+
+	var paths []string
+	for i, s := range current {
+		if reloadable(s.Path) || reflect.DeepEqual(s.Value.Interface(), changed[i].Value.Interface()) {
+			continue
+		}
+		paths = append(paths, s.Path)
+	}
+	return paths
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Masked replaces the value of a set secret in printed configs.
+const Masked = "******"
+
+// setting is a leaf field of the config.
+type setting struct {
+	// Path is the YAML path, such as database.password.
+	Path string
+	// Env is the environment variable that overrides it, such as DB_PASSWORD.
+	Env    string
+	Secret bool
+	Value  reflect.Value
+}
+
+// settings lists the leaf fields of the struct v points to.
+func settings(v reflect.Value) []setting {
+	var result []setting
+	var walk func(v reflect.Value, path, envPrefix string)
+	walk = func(v reflect.Value, path, envPrefix string) {
+		t := v.Type()
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name := path + field.Tag.Get("yaml")
+			value := v.Field(i)
+			if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeFor[time.Time]() {
+				walk(value, name+".", envPrefix+field.Tag.Get("env-prefix"))
+				continue
+			}
+			result = append(result, setting{
+				Path:   name,
+				Env:    envPrefix + field.Tag.Get("env"),
+				Secret: field.Tag.Get("secret") == "true",
+				Value:  value,
+			})
+		}
+	}
+	walk(v.Elem(), "", "")
+	return result
+}
+
+// readSecretFiles reads secrets from the files named by their _FILE
+// variables, which win over the plain ones.
+func readSecretFiles(c *Config) error {
+	for _, s := range settings(reflect.ValueOf(c)) {
+		if !s.Secret {
+			continue
+		}
+		path := os.Getenv(s.Env + "_FILE")
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read %s_FILE: %w", s.Env, err)
+		}
+		s.Value.SetString(strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// Print writes the effective config as YAML with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	masked := *c
+	for _, s := range settings(reflect.ValueOf(&masked)) {
+		if s.Secret && s.Value.String() != "" {
+			s.Value.SetString(Masked)
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&masked); err != nil {
+		return err
+	}
+	return enc.Close()
+}
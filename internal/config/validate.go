@@ -0,0 +1,152 @@
+package config
+
+import (
+	"chats/internal/logging"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Validate checks the whole config and reports every invalid setting at
+// once, so a broken deployment is fixed in one round.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		v.fail("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	v.positive("limits.max_message_length", c.Limits.MaxMessageLength)
+	v.positive("limits.max_title_length", c.Limits.MaxTitleLength)
+	v.positive("limits.default_history", c.Limits.DefaultHistory)
+	v.positive("limits.max_history", c.Limits.MaxHistory)
+	if c.Limits.DefaultHistory > c.Limits.MaxHistory {
+		v.fail("limits.default_history", "must not exceed limits.max_history (%d)", c.Limits.MaxHistory)
+	}
+
+	v.required("database.host", c.DB.Host)
+	v.port("database.port", c.DB.Port)
+	v.required("database.dbname", c.DB.DBName)
+	v.required("database.user", c.DB.User)
+	v.oneOf("database.sslmode", c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.oneOf("database.migrations", c.DB.Migrations, "up", "check")
+	v.oneOf("database.log_level", c.DB.LogLevel, "silent", "error", "warn", "info")
+	v.positiveDuration("database.connect_timeout", c.DB.ConnectTimeout)
+	v.positive("database.max_open_conns", c.DB.MaxOpenConns)
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		v.fail("database.max_idle_conns", "must be between 0 and database.max_open_conns (%d)", c.DB.MaxOpenConns)
+	}
+	v.nonNegativeDuration("database.conn_max_lifetime", c.DB.ConnMaxLifetime)
+	v.nonNegativeDuration("database.conn_max_idle_time", c.DB.ConnMaxIdleTime)
+	v.nonNegativeDuration("database.statement_timeout", c.DB.StatementTimeout)
+	v.nonNegativeDuration("database.query_timeout", c.DB.QueryTimeout)
+	for _, entry := range c.DB.Replicas {
+		host, port := c.DB.ReplicaAddress(entry)
+		v.required("database.replicas", host)
+		v.port("database.replicas", port)
+	}
+	if len(c.DB.Replicas) > 0 {
+		v.positiveDuration("database.replica_check_interval", c.DB.ReplicaCheckInterval)
+	}
+	v.nonNegativeDuration("database.replica_max_lag", c.DB.ReplicaMaxLag)
+	v.nonNegativeDuration("database.read_your_writes", c.DB.ReadYourWrites)
+
+	v.port("http_server.port", c.Server.Port)
+	v.positiveDuration("http_server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	if c.Server.MaxBodyBytes <= 0 {
+		v.fail("http_server.max_body_bytes", "must be positive, got %d", c.Server.MaxBodyBytes)
+	}
+	if _, err := c.Server.TrustedPrefixes(); err != nil {
+		v.fail("http_server.trusted_proxies", "%s", err)
+	}
+	v.port("grpc_server.port", c.GRPC.Port)
+
+	v.positive("webhooks.workers", c.Webhooks.Workers)
+	v.positiveDuration("webhooks.poll_interval", c.Webhooks.PollInterval)
+	v.positive("webhooks.max_attempts", c.Webhooks.MaxAttempts)
+	v.positiveDuration("webhooks.timeout", c.Webhooks.Timeout)
+	v.positiveDuration("webhooks.base_backoff", c.Webhooks.BaseBackoff)
+	if c.Webhooks.MaxBackoff < c.Webhooks.BaseBackoff {
+		v.fail("webhooks.max_backoff", "must not be less than webhooks.base_backoff (%s)", c.Webhooks.BaseBackoff)
+	}
+
+	v.positiveDuration("outbox.poll_interval", c.Outbox.PollInterval)
+	v.positive("outbox.batch_size", c.Outbox.BatchSize)
+	v.positiveDuration("outbox.cleanup_interval", c.Outbox.CleanupInterval)
+	v.positiveDuration("idempotency.ttl", c.Idempotency.TTL)
+	v.positiveDuration("idempotency.cleanup_interval", c.Idempotency.CleanupInterval)
+	v.positiveDuration("idempotency.lock_timeout", c.Idempotency.LockTimeout)
+	v.positiveDuration("realtime.poll_interval", c.Realtime.PollInterval)
+
+	v.positive("graphql.max_depth", c.GraphQL.MaxDepth)
+	v.positive("graphql.max_complexity", c.GraphQL.MaxComplexity)
+
+	if !c.API.V1DeprecatedAt.IsZero() && !c.API.V1Sunset.IsZero() && c.API.V1Sunset.Before(c.API.V1DeprecatedAt) {
+		v.fail("api.v1_sunset", "must not be before api.v1_deprecated_at (%s)", c.API.V1DeprecatedAt.Format(time.DateOnly))
+	}
+
+	v.positive("import.batch_size", c.Import.BatchSize)
+	v.positive("batch.max_operations", c.Batch.MaxOperations)
+	if c.Compression.MinSize < 0 {
+		v.fail("compression.min_size", "must not be negative")
+	}
+
+	v.positiveDuration("shutdown.timeout", c.Shutdown.Timeout)
+	v.positiveDuration("health.check_timeout", c.Health.CheckTimeout)
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, "none", "stdout", "otlp")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.fail("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n%w", ErrInvalid, errors.Join(v.errs...))
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) required(field, value string) {
+	if value == "" {
+		v.fail(field, "is required")
+	}
+}
+
+func (v *validator) positive(field string, value int) {
+	if value <= 0 {
+		v.fail(field, "must be positive, got %d", value)
+	}
+}
+
+func (v *validator) positiveDuration(field string, value time.Duration) {
+	if value <= 0 {
+		v.fail(field, "must be positive, got %s", value)
+	}
+}
+
+func (v *validator) nonNegativeDuration(field string, value time.Duration) {
+	if value < 0 {
+		v.fail(field, "must not be negative, got %s", value)
+	}
+}
+
+func (v *validator) port(field, value string) {
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		v.fail(field, "must be a port number, got %q", value)
+	}
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.fail(field, "must be one of %v, got %q", allowed, value)
+	}
+}
@@ -2,10 +2,15 @@ package database
 
 import (
 	"chats/internal/config"
+	"chats/internal/helpers"
+	"chats/internal/logging"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
-	"github.com/pressly/goose"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,43 +18,129 @@ import (
 
 type Database struct {
 	DB *gorm.DB
+
+	// migrationDSN reaches the primary without the statement timeout.
+	migrationDSN string
+
+	replicas      []*replica
+	replicaMaxLag time.Duration
 }
 
-func NewDatabase(config config.DatabaseConfig) (*Database, error) {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		config.Host, config.User, config.Password, config.DBName, config.Port, config.SSLMode,
-	)
+// Backoff bounds between connection attempts at startup.
+const (
+	connectBaseBackoff = 500 * time.Millisecond
+	connectMaxBackoff  = 10 * time.Second
+)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// NewDatabase connects to the database. While Postgres is not
+// reachable yet, e.g. when both start together, it retries with backoff
+// for up to config.ConnectTimeout or until ctx is done. Reads are routed
+// to config.Replicas, if any; see MonitorReplicas and ReadYourWrites.
+func NewDatabase(ctx context.Context, config config.DatabaseConfig) (*Database, error) {
+	gormLogger, err := logging.GORMLogger(logging.GORMOptions{
+		Level:         config.LogLevel,
+		SlowThreshold: config.SlowQueryThreshold,
+		LogParams:     config.LogParams,
 	})
+	if err != nil {
+		return nil, err
+	}
 
+	db, err := connect(ctx, dsn(config, config.Host, config.Port), config.ConnectTimeout, gormLogger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Connected to database successfully")
+	slog.Default().Info("Connected to database")
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("Failed to get sql.DB:", err)
+		return nil, err
+	}
+	configurePool(sqlDB, config)
+	if err := db.Use(timeouts{}); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
 	}
 
-	err = goose.Up(sqlDB, "database/migrations")
-	if err != nil {
-		log.Fatal("Failed to run migrations:", err)
+	unlimited := config
+	unlimited.StatementTimeout = 0
+	d := &Database{DB: db, migrationDSN: dsn(unlimited, config.Host, config.Port)}
+	if len(config.Replicas) > 0 {
+		if err := d.useReplicas(ctx, config, gormLogger); err != nil {
+			_ = d.Close()
+			return nil, fmt.Errorf("failed to set up replicas: %w", err)
+		}
 	}
+	return d, nil
+}
 
-	log.Println("Database migrated successfully")
+// dsn reaches host and port with config's credentials. Its sessions run
+// with config.StatementTimeout, which pgx passes on as a runtime parameter.
+func dsn(config config.DatabaseConfig, host, port string) string {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		host, config.User, config.Password, config.DBName, port, config.SSLMode,
+	)
+	if config.StatementTimeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", config.StatementTimeout.Milliseconds())
+	}
+	return dsn
+}
+
+// configurePool applies the pool settings, which are per pool: the
+// primary and every replica get their own.
+func configurePool(db *sql.DB, config config.DatabaseConfig) {
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+}
+
+func connect(ctx context.Context, dsn string, timeout time.Duration, gormLogger logger.Interface) (*gorm.DB, error) {
+	deadline := time.Now().Add(timeout)
+
+	for attempt := 1; ; attempt++ {
+		// gorm.Open pings, so a returned DB is reachable.
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: gormLogger,
+		})
+		if err == nil {
+			return db, nil
+		}
+
+		delay := helpers.Backoff(attempt, connectBaseBackoff, connectMaxBackoff)
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		slog.Default().Warn("Database not reachable, retrying", "attempt", attempt, "retry_in", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (last error: %w)", context.Cause(ctx), err)
+		case <-time.After(delay):
+		}
+	}
+}
 
-	return &Database{DB: db}, nil
+// Close closes the connection pools.
+func (d *Database) Close() error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+	errs := []error{sqlDB.Close()}
+	for _, r := range d.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
 }
 
-func (d *Database) HealthCheck() error {
+// HealthCheck pings the database.
+func (d *Database) HealthCheck(ctx context.Context) error {
 	sqlDB, err := d.DB.DB()
 	if err != nil {
 		return err
 	}
-	return sqlDB.Ping()
+	return sqlDB.PingContext(ctx)
 }
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"chats/database/migrations"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // the pgx database/sql driver
+	"github.com/pressly/goose"
+)
+
+// migrationLockKey is the advisory lock that serializes migration runs.
+const migrationLockKey int64 = 0x63686174735f6d67 // "chats_mg"
+
+// MigrationsDir is where migrate create writes new migrations, relative to
+// the repository root; they are embedded into the binary on the next build.
+const MigrationsDir = "database/migrations"
+
+// MigrationStatus describes one embedded migration.
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	// AppliedAt is zero while the migration is pending.
+	AppliedAt time.Time
+}
+
+// MigrateUp applies all pending migrations.
+func (d *Database) MigrateUp(ctx context.Context) error {
+	return d.withMigrations(ctx, func(sqlDB *sql.DB, dir string) error {
+		return goose.Up(sqlDB, dir)
+	})
+}
+
+// MigrateDown rolls back the latest applied migration.
+func (d *Database) MigrateDown(ctx context.Context) error {
+	return d.withMigrations(ctx, func(sqlDB *sql.DB, dir string) error {
+		return goose.Down(sqlDB, dir)
+	})
+}
+
+// MigrateRedo rolls back the latest applied migration and applies it again.
+func (d *Database) MigrateRedo(ctx context.Context) error {
+	return d.withMigrations(ctx, func(sqlDB *sql.DB, dir string) error {
+		return goose.Redo(sqlDB, dir)
+	})
+}
+
+// MigrationStatus lists the embedded migrations in order with the time
+// each one was applied.
+func (d *Database) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	names, err := migrationNames()
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	// Creates the version table on a pristine database.
+	if _, err := goose.EnsureDBVersion(sqlDB); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT tstamp, is_applied FROM %s WHERE version_id = $1 ORDER BY id DESC LIMIT 1", goose.TableName())
+	result := make([]MigrationStatus, 0, len(names))
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			return nil, err
+		}
+
+		status := MigrationStatus{Version: version, Name: name}
+		var appliedAt time.Time
+		var applied bool
+		err = sqlDB.QueryRowContext(ctx, query, version).Scan(&appliedAt, &applied)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read status of %s: %w", name, err)
+		case applied:
+			status.AppliedAt = appliedAt
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// CheckMigrations reports an error while the schema is behind the latest
+// embedded migration. A schema ahead of it passes, so an older binary keeps
+// serving during a rolling deploy or rollback.
+func (d *Database) CheckMigrations(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	latest, err := latestVersion()
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+	current, err := goose.GetDBVersion(sqlDB)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return checkSchemaVersion(current, latest)
+}
+
+func checkSchemaVersion(current, latest int64) error {
+	if current < latest {
+		return fmt.Errorf("schema version is %d, want at least %d", current, latest)
+	}
+	return nil
+}
+
+// CreateMigration writes an empty SQL migration named after name to dir.
+func CreateMigration(dir, name string) error {
+	return goose.Create(nil, dir, name, "sql")
+}
+
+// withMigrations runs fn under the migration lock with the embedded
+// migrations extracted to dir, as goose reads them from disk.
+func (d *Database) withMigrations(ctx context.Context, fn func(sqlDB *sql.DB, dir string) error) error {
+	dir, err := os.MkdirTemp("", "chats-migrations-")
+	if err != nil {
+		return fmt.Errorf("failed to extract migrations: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.CopyFS(dir, migrations.FS); err != nil {
+		return fmt.Errorf("failed to extract migrations: %w", err)
+	}
+
+	// A pool of its own, without the statement timeout.
+	sqlDB, err := sql.Open("pgx", d.migrationDSN)
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	// The lock belongs to the session, so it needs a dedicated connection.
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	slog.Default().Info("Waiting for migration lock")
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// The caller's context may be done by now; the unlock must still run.
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			slog.Default().Warn("Failed to release migration lock", "error", err)
+		}
+	}()
+
+	return fn(sqlDB, dir)
+}
+
+func migrationNames() ([]string, error) {
+	names, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		va, _ := goose.NumericComponent(a)
+		vb, _ := goose.NumericComponent(b)
+		return cmp.Compare(va, vb)
+	})
+	return names, nil
+}
+
+func latestVersion() (int64, error) {
+	names, err := migrationNames()
+	if err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		return 0, errors.New("no migrations embedded")
+	}
+	return goose.NumericComponent(names[len(names)-1])
+}
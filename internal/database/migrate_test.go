@@ -0,0 +1,43 @@
+package database
+
+import (
+	"chats/database/migrations"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/pressly/goose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	names, err := migrationNames()
+	require.NoError(t, err)
+	require.NotEmpty(t, names)
+
+	assert.True(t, strings.HasSuffix(names[0], "_create_chats_table.sql"), "sorted by version")
+
+	var previous int64
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		require.NoError(t, err)
+		assert.Greater(t, version, previous, "%s has a duplicate version", name)
+		previous = version
+
+		data, err := fs.ReadFile(migrations.FS, name)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "-- +goose Up", name)
+		assert.Contains(t, string(data), "-- +goose Down", name)
+	}
+
+	latest, err := latestVersion()
+	require.NoError(t, err)
+	assert.Equal(t, previous, latest)
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	assert.NoError(t, checkSchemaVersion(20261019170000, 20261019170000))
+	assert.NoError(t, checkSchemaVersion(20261101000000, 20261019170000), "a schema ahead of the binary")
+	assert.Error(t, checkSchemaVersion(20261001000000, 20261019170000))
+}
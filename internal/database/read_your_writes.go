@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type sessionKey struct{}
+
+// session follows the statements run with one ctx, usually a request's.
+type session struct {
+	primary atomic.Bool
+	onWrite func()
+}
+
+// ReadYourWrites returns a ctx whose reads go to the primary once a write
+// made with it succeeds, or from the start when primary is set, so a
+// caller sees its own changes even while replicas lag. onWrite, if not
+// nil, is called after every such write. It has no effect without replicas.
+func ReadYourWrites(ctx context.Context, primary bool, onWrite func()) context.Context {
+	s := &session{onWrite: onWrite}
+	s.primary.Store(primary)
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+func sessionFrom(db *gorm.DB) *session {
+	if db.Statement.Context == nil {
+		return nil
+	}
+	s, _ := db.Statement.Context.Value(sessionKey{}).(*session)
+	return s
+}
+
+// readYourWrites is the GORM plugin behind ReadYourWrites. It is
+// registered after dbresolver, whose routing it overrides.
+type readYourWrites struct{}
+
+func (readYourWrites) Name() string {
+	return "read_your_writes"
+}
+
+func (p readYourWrites) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Query().After("gorm:db_resolver").Register("read_your_writes:route_query", p.route),
+		cb.Row().After("gorm:db_resolver").Register("read_your_writes:route_row", p.route),
+		cb.Query().After("*").Register("read_your_writes:after_query", p.afterRead),
+		cb.Row().After("*").Register("read_your_writes:after_row", p.afterRead),
+		cb.Create().After("*").Register("read_your_writes:after_create", p.afterWrite),
+		cb.Update().After("*").Register("read_your_writes:after_update", p.afterWrite),
+		cb.Delete().After("*").Register("read_your_writes:after_delete", p.afterWrite),
+		cb.Raw().After("*").Register("read_your_writes:after_raw", p.afterWrite),
+	)
+}
+
+// route sends the reads of a session that wrote to the primary.
+// ModifyStatement runs dbresolver's routing again with the write mode set.
+func (readYourWrites) route(db *gorm.DB) {
+	if s := sessionFrom(db); s != nil && s.primary.Load() {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
+
+// afterRead counts raw statements such as UPDATE ... RETURNING, which run
+// as queries, as writes.
+func (p readYourWrites) afterRead(db *gorm.DB) {
+	sql := strings.TrimSpace(db.Statement.SQL.String())
+	if sql == "" || len(sql) >= 6 && strings.EqualFold(sql[:6], "select") {
+		return
+	}
+	p.afterWrite(db)
+}
+
+func (readYourWrites) afterWrite(db *gorm.DB) {
+	s := sessionFrom(db)
+	if s == nil || db.Error != nil {
+		return
+	}
+	s.primary.Store(true)
+	if s.onWrite != nil {
+		s.onWrite()
+	}
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"chats/internal/config"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replica is a read-only copy of the primary that reads can be routed to.
+type replica struct {
+	addr    string
+	db      *sql.DB
+	healthy atomic.Bool
+	// checked is set after the first check, so its result is always logged.
+	checked bool
+}
+
+// replicaPolicy spreads reads round-robin over the healthy replicas and
+// falls back to the primary while none is healthy.
+type replicaPolicy struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// Resolve implements dbresolver.Policy. The pools passed in are the
+// replicas' own *sql.DBs, so it picks from its list, which knows their health.
+func (p *replicaPolicy) Resolve([]gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]*replica, 0, len(p.replicas))
+	for _, r := range p.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return p.primary
+	}
+	n := p.next.Add(1) - 1
+	return healthy[n%uint64(len(healthy))].db
+}
+
+// useReplicas routes reads to the configured replicas. They start out of
+// rotation and join once the first check, made before it returns, passes.
+func (d *Database) useReplicas(ctx context.Context, config config.DatabaseConfig, gormLogger logger.Interface) error {
+	primary, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	policy := &replicaPolicy{primary: primary}
+	dialectors := make([]gorm.Dialector, 0, len(config.Replicas))
+	for _, entry := range config.Replicas {
+		host, port := config.ReplicaAddress(entry)
+		// Only sets up the pool: a replica that is down is left out of
+		// rotation by the checks instead of failing startup.
+		conn, err := gorm.Open(postgres.Open(dsn(config, host, port)), &gorm.Config{
+			Logger:               gormLogger,
+			DisableAutomaticPing: true,
+		})
+		if err != nil {
+			return fmt.Errorf("replica %s: %w", entry, err)
+		}
+		sqlDB, err := conn.DB()
+		if err != nil {
+			return fmt.Errorf("replica %s: %w", entry, err)
+		}
+		configurePool(sqlDB, config)
+
+		policy.replicas = append(policy.replicas, &replica{addr: net.JoinHostPort(host, port), db: sqlDB})
+		dialectors = append(dialectors, postgres.New(postgres.Config{Conn: sqlDB}))
+	}
+	d.replicas = policy.replicas
+	d.replicaMaxLag = config.ReplicaMaxLag
+
+	// dbresolver opens the dialectors with the primary's gorm.Config, which
+	// would ping them. The primary is connected already, so this is only
+	// read for the replicas.
+	d.DB.Config.DisableAutomaticPing = true
+	err = d.DB.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   policy,
+	}))
+	if err != nil {
+		return err
+	}
+	if err := d.DB.Use(readYourWrites{}); err != nil {
+		return err
+	}
+
+	d.checkReplicas(ctx, config.ReplicaCheckInterval)
+	return nil
+}
+
+// Replicas returns the replicas' connection pools by address.
+func (d *Database) Replicas() map[string]*sql.DB {
+	pools := make(map[string]*sql.DB, len(d.replicas))
+	for _, r := range d.replicas {
+		pools[r.addr] = r.db
+	}
+	return pools
+}
+
+// MonitorReplicas checks the replicas every interval until ctx is done,
+// taking failing or lagging ones out of rotation and putting them back
+// once they recover.
+func (d *Database) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	if len(d.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkReplicas(ctx, interval)
+		}
+	}
+}
+
+func (d *Database) checkReplicas(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, r := range d.replicas {
+		wg.Go(func() {
+			err := r.check(ctx, d.replicaMaxLag)
+			healthy := err == nil
+			if r.healthy.Swap(healthy) == healthy && r.checked {
+				return
+			}
+			r.checked = true
+
+			if healthy {
+				slog.Default().Info("Replica in rotation", "replica", r.addr)
+			} else {
+				slog.Default().Warn("Replica out of rotation", "replica", r.addr, "error", err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// lagQuery reports how far a replica is behind. One that has replayed all
+// it received is current even if the last replayed transaction is old
+// because the primary has been idle; a server that is not a standby,
+// such as a second local instance, reports no lag.
+const lagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END::float8`
+
+func (r *replica) check(ctx context.Context, maxLag time.Duration) error {
+	var seconds float64
+	if err := r.db.QueryRowContext(ctx, lagQuery).Scan(&seconds); err != nil {
+		return err
+	}
+	lag := time.Duration(seconds * float64(time.Second))
+	if maxLag > 0 && lag > maxLag {
+		return fmt.Errorf("replication lag %s exceeds %s", lag.Round(time.Millisecond), maxLag)
+	}
+	return nil
+}
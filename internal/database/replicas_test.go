@@ -0,0 +1,89 @@
+package database
+
+import (
+	"chats/internal/domain"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+// openPool returns a pool that is never connected; DryRun statements
+// only need to be routed to it.
+func openPool(t *testing.T, host string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("pgx", "host="+host)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestReplicaPolicy(t *testing.T) {
+	primary := openPool(t, "primary")
+	a := &replica{addr: "a", db: openPool(t, "a")}
+	b := &replica{addr: "b", db: openPool(t, "b")}
+	policy := &replicaPolicy{primary: primary, replicas: []*replica{a, b}}
+
+	assert.Same(t, primary, policy.Resolve(nil), "no healthy replica falls back to the primary")
+
+	a.healthy.Store(true)
+	b.healthy.Store(true)
+	assert.Same(t, a.db, policy.Resolve(nil))
+	assert.Same(t, b.db, policy.Resolve(nil), "round-robin")
+	assert.Same(t, a.db, policy.Resolve(nil))
+
+	a.healthy.Store(false)
+	for range 3 {
+		assert.Same(t, b.db, policy.Resolve(nil), "an unhealthy replica is out of rotation")
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	primary, replicaDB := openPool(t, "primary"), openPool(t, "replica")
+	r := &replica{addr: "replica", db: replicaDB}
+	r.healthy.Store(true)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: primary}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{postgres.New(postgres.Config{Conn: replicaDB})},
+		Policy:   &replicaPolicy{primary: primary, replicas: []*replica{r}},
+	})))
+	require.NoError(t, db.Use(readYourWrites{}))
+
+	pool := func(tx *gorm.DB) gorm.ConnPool {
+		require.NoError(t, tx.Error)
+		return tx.Statement.ConnPool
+	}
+	var chats []domain.Chat
+
+	assert.Same(t, replicaDB, pool(db.WithContext(context.Background()).Find(&chats)), "reads go to the replica")
+
+	ctx := ReadYourWrites(context.Background(), true, nil)
+	assert.Same(t, primary, pool(db.WithContext(ctx).Find(&chats)), "a sticky caller reads from the primary")
+
+	writes := 0
+	ctx = ReadYourWrites(context.Background(), false, func() { writes++ })
+	assert.Same(t, replicaDB, pool(db.WithContext(ctx).Find(&chats)))
+	assert.Zero(t, writes)
+
+	assert.Same(t, primary, pool(db.WithContext(ctx).Create(&domain.Chat{Title: "new"})), "writes go to the primary")
+	assert.Equal(t, 1, writes)
+	assert.Same(t, primary, pool(db.WithContext(ctx).Find(&chats)), "reads after a write go to the primary")
+
+	ctx = ReadYourWrites(context.Background(), false, func() { writes++ })
+	var disabled bool
+	db.WithContext(ctx).Raw("UPDATE webhooks SET active = false WHERE id = 1 RETURNING NOT active").Scan(&disabled)
+	assert.Equal(t, 2, writes, "a raw UPDATE counts as a write")
+}
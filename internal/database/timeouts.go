@@ -0,0 +1,77 @@
+package database
+
+import (
+	"chats/internal/domain"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// TranslateError wraps errors meaning the database gave up on a statement
+// or cannot serve one right now in domain.ErrTimeout or
+// domain.ErrUnavailable, so they are reported as such rather than as
+// internal errors. Cancellation by the caller is returned as is: nobody
+// is waiting for the answer.
+func TranslateError(err error) error {
+	switch {
+	case err == nil || errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, domain.ErrTimeout) || errors.Is(err, domain.ErrUnavailable):
+		return err
+	case errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err):
+		return fmt.Errorf("%w: %w", domain.ErrTimeout, err)
+	case errors.Is(err, driver.ErrBadConn):
+		return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch code := pgErr.Code; {
+		// query_canceled, mostly by statement_timeout, lock_not_available
+		// and idle_in_transaction_session_timeout.
+		case code == "57014", code == "55P03", code == "25P03":
+			return fmt.Errorf("%w: %w", domain.ErrTimeout, err)
+		// Shutdowns, too_many_connections and connection exceptions.
+		case strings.HasPrefix(code, "57P"), code == "53300", strings.HasPrefix(code, "08"):
+			return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+	}
+	return err
+}
+
+// timeouts is the GORM plugin that translates statement errors. It sets no
+// deadline of its own: statements run with the caller's context, whose
+// deadline covers the whole request or call rather than each statement.
+type timeouts struct{}
+
+func (timeouts) Name() string {
+	return "timeouts"
+}
+
+func (p timeouts) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().After("*").Register("timeouts:after_create", p.after),
+		cb.Query().After("*").Register("timeouts:after_query", p.after),
+		cb.Update().After("*").Register("timeouts:after_update", p.after),
+		cb.Delete().After("*").Register("timeouts:after_delete", p.after),
+		cb.Row().After("*").Register("timeouts:after_row", p.after),
+		cb.Raw().After("*").Register("timeouts:after_raw", p.after),
+	)
+}
+
+func (timeouts) after(db *gorm.DB) {
+	db.Error = TranslateError(db.Error)
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"chats/internal/domain"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), domain.ErrTimeout},
+		{"statement timeout", &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}, domain.ErrTimeout},
+		{"lock timeout", &pgconn.PgError{Code: "55P03"}, domain.ErrTimeout},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, domain.ErrUnavailable},
+		{"too many connections", &pgconn.PgError{Code: "53300"}, domain.ErrUnavailable},
+		{"bad connection", driver.ErrBadConn, domain.ErrUnavailable},
+		{"already translated", fmt.Errorf("%w: %w", domain.ErrTimeout, context.DeadlineExceeded), domain.ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TranslateError(tt.err)
+			assert.ErrorIs(t, got, tt.want)
+			assert.ErrorIs(t, got, tt.err, "keeps the cause")
+		})
+	}
+
+	assert.Equal(t, "database timed out: context deadline exceeded", TranslateError(TranslateError(context.DeadlineExceeded)).Error(), "translates once")
+
+	for _, err := range []error{
+		context.Canceled,
+		&pgconn.PgError{Code: "23505"},
+		gorm.ErrRecordNotFound,
+		errors.New("boom"),
+	} {
+		assert.Same(t, err, TranslateError(err), "%v is left alone", err)
+	}
+	assert.NoError(t, TranslateError(nil))
+}
+
+func TestTimeouts(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: openPool(t, "primary")}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(timeouts{}))
+
+	var hasDeadline bool
+	require.NoError(t, db.Callback().Query().Before("timeouts:after_query").Register("test:deadline", func(db *gorm.DB) {
+		_, hasDeadline = db.Statement.Context.Deadline()
+		if err := db.Statement.Context.Err(); err != nil {
+			_ = db.AddError(err)
+		}
+	}))
+
+	var chats []domain.Chat
+	require.NoError(t, db.WithContext(context.Background()).Find(&chats).Error)
+	assert.False(t, hasDeadline, "statements get no deadline of their own")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	err = db.WithContext(ctx).Find(&chats).Error
+	assert.True(t, hasDeadline, "the caller's deadline applies")
+	assert.ErrorIs(t, err, domain.ErrTimeout)
+}
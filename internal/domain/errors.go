@@ -2,27 +2,64 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrInvalidInput  = errors.New("invalid input")
 	ErrAlreadyExists = errors.New("already exists")
+	// ErrVersionMismatch means the resource changed since the version the
+	// caller based its change on.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrTimeout means the database did not answer in time and
+	// ErrUnavailable that it cannot serve requests right now; either may
+	// succeed when retried.
+	ErrTimeout     = errors.New("database timed out")
+	ErrUnavailable = errors.New("database unavailable")
 )
 
-type APIError struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
+// Invalid message texts; both are ErrInvalidInput.
+var (
+	ErrEmptyText   = NewError(ErrInvalidInput, "message text is empty")
+	ErrTextTooLong = NewError(ErrInvalidInput, "message text is too long")
+)
+
+// ErrWebhookDisabled rejects redelivering to a disabled webhook.
+var ErrWebhookDisabled = NewError(ErrInvalidInput, "webhook is disabled, enable it first")
+
+// Error is one of the errors above with a message written for clients.
+// Err, when set, is wrapped as well but its text is not part of Message.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+// NewError returns an *Error of kind with a formatted message. The message
+// is shown to clients, so it must not be built from other errors.
+func NewError(kind error, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Message
 }
 
-func NewAPIError(status int, message string, err error) APIError {
-	apiErr := APIError{
-		Status:  status,
-		Message: message,
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
 	}
-	if err != nil {
-		apiErr.Error = err.Error()
+	return []error{e.Kind, e.Err}
+}
+
+// Describe returns what clients are told about err, which is kind: the
+// text of the *Error of that kind in its chain, or else kind's own. The rest
+// of the chain may hold repository or driver errors and is never shown.
+func Describe(err, kind error) string {
+	var e *Error
+	if errors.As(err, &e) && errors.Is(e.Kind, kind) {
+		return e.Error()
 	}
-	return apiErr
+	return kind.Error()
 }
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventChatCreated    EventType = "chat.created"
+	EventChatUpdated    EventType = "chat.updated"
+	EventChatDeleted    EventType = "chat.deleted"
+	EventMessageCreated EventType = "message.created"
+	EventMessageUpdated EventType = "message.updated"
+	EventMessageDeleted EventType = "message.deleted"
+)
+
+var EventTypes = []EventType{
+	EventChatCreated,
+	EventChatUpdated,
+	EventChatDeleted,
+	EventMessageCreated,
+	EventMessageUpdated,
+	EventMessageDeleted,
+}
+
+func (t EventType) Valid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Event struct {
+	ID         string          `json:"id"`
+	Type       EventType       `json:"type"`
+	ChatID     uint            `json:"chat_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func NewEvent(eventType EventType, chatID uint, data any) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:         hex.EncodeToString(id),
+		Type:       eventType,
+		ChatID:     chatID,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key. StatusCode is zero while the first request is in flight;
+// after LockedUntil such a claim, left behind by a crashed request, can be
+// taken over.
+type IdempotencyRecord struct {
+	Scope           string `gorm:"primary_key"`
+	Key             string `gorm:"primary_key"`
+	RequestHash     string `gorm:"not null"`
+	StatusCode      int    `gorm:"not null"`
+	ResponseHeaders Header `gorm:"type:jsonb;not null"`
+	// ContentType is only set by releases before ResponseHeaders.
+	ContentType  string `gorm:"->"`
+	ResponseBody []byte
+	CreatedAt    time.Time
+	LockedUntil  time.Time `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
+
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// Header is a set of HTTP headers stored as a JSON object.
+type Header map[string][]string
+
+func (h Header) Value() (driver.Value, error) {
+	if h == nil {
+		h = Header{}
+	}
+	b, err := json.Marshal(map[string][]string(h))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (h *Header) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*h = nil
+		return nil
+	default:
+		return errors.New("unsupported type for Header")
+	}
+	return json.Unmarshal(b, (*map[string][]string)(h))
+}
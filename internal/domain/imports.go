@@ -0,0 +1,21 @@
+package domain
+
+// ImportLineError reports why one NDJSON line was not imported. Line counts
+// from 1 over the whole uploaded body, including skipped lines.
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarizes a message import. NextOffset is the number of
+// leading lines that are fully handled: resending the same body with
+// offset=NextOffset resumes without duplicating committed messages.
+type ImportReport struct {
+	Skipped    int               `json:"skipped"`
+	Imported   int               `json:"imported"`
+	Failed     int               `json:"failed"`
+	NextOffset int               `json:"next_offset"`
+	Errors     []ImportLineError `json:"errors"`
+	// ErrorsTruncated is set when more lines failed than Errors lists.
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
@@ -3,15 +3,46 @@ package domain
 import "time"
 
 type Chat struct {
-	ID        uint      `json:"id" gorm:"primary_key"`
-	Title     string    `json:"title" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
+	ID      uint   `json:"id" gorm:"primary_key"`
+	Title   string `json:"title" gorm:"not null"`
+	LastSeq uint64 `json:"last_seq" gorm:"not null;default:0"`
+	// Version increases on every change to the chat itself and is what
+	// If-Match checks.
+	Version uint64 `json:"version" gorm:"not null;default:1"`
+	// HistoryVersion increases on every message write. It is part of the
+	// chat's ETag, which covers the embedded messages, but not of If-Match.
+	HistoryVersion uint64    `json:"-" gorm:"not null;default:0"`
+	CreatedAt      time.Time `json:"created_at"`
+	// UpdatedAt moves with Version and HistoryVersion.
+	UpdatedAt time.Time `json:"updated_at"`
 	Message   []Message `json:"message,omitempty" gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
 }
 
 type Message struct {
-	ID        uint      `json:"id" gorm:"primary_key"`
-	ChatID    uint      `json:"chat_id" gorm:"not null"`
-	Text      string    `json:"text" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint       `json:"id" gorm:"primary_key"`
+	ChatID    uint       `json:"chat_id" gorm:"not null"`
+	Seq       uint64     `json:"seq" gorm:"not null"`
+	Text      string     `json:"text" gorm:"not null"`
+	Author    string     `json:"author,omitempty" gorm:"not null;default:''"`
+	Version   uint64     `json:"version" gorm:"not null;default:1"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+}
+
+// MessageCursor selects a page of a chat's history by seq. AfterSeq resumes
+// forward from a known message; otherwise the page ends before BeforeSeq, or
+// at the latest message when BeforeSeq is zero.
+type MessageCursor struct {
+	AfterSeq  uint64
+	BeforeSeq uint64
+	Limit     int
+}
+
+// HistoryFilter selects the messages of a chat for an export. Zero From or
+// To leave that side of the created_at range open; To is exclusive. UpToSeq
+// pins the export to the history that existed when it started.
+type HistoryFilter struct {
+	From    time.Time
+	To      time.Time
+	UpToSeq uint64
 }
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEntry is an event stored in the same transaction as the change that
+// produced it, waiting to be relayed to subscribers.
+type OutboxEntry struct {
+	ID          uint64    `gorm:"primary_key"`
+	EventID     string    `gorm:"not null"`
+	EventType   EventType `gorm:"not null"`
+	ChatID      uint      `gorm:"not null"`
+	Payload     string    `gorm:"type:jsonb;not null"`
+	Attempts    int       `gorm:"not null"`
+	LastError   string    `gorm:"not null"`
+	AvailableAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	ProcessedAt *time.Time
+	CreatedAt   time.Time
+}
+
+func (OutboxEntry) TableName() string {
+	return "outbox"
+}
+
+func NewOutboxEntry(event Event) (*OutboxEntry, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEntry{
+		EventID:   event.ID,
+		EventType: event.Type,
+		ChatID:    event.ChatID,
+		Payload:   string(payload),
+	}, nil
+}
+
+func (e OutboxEntry) Event() (Event, error) {
+	var event Event
+	err := json.Unmarshal([]byte(e.Payload), &event)
+	return event, err
+}
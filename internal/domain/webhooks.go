@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type Webhook struct {
+	ID                  uint          `json:"id" gorm:"primary_key"`
+	URL                 string        `json:"url" gorm:"not null"`
+	ChatID              *uint         `json:"chat_id,omitempty"`
+	EventTypes          EventTypeList `json:"event_types" gorm:"type:jsonb;not null"`
+	Secret              string        `json:"-" gorm:"not null"`
+	Active              bool          `json:"active"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	DisabledAt          *time.Time    `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time     `json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a single HTTP attempt. Retries of the same event share EventID.
+type WebhookDelivery struct {
+	ID             uint           `json:"id" gorm:"primary_key"`
+	WebhookID      uint           `json:"webhook_id" gorm:"not null"`
+	EventID        string         `json:"event_id" gorm:"not null"`
+	EventType      EventType      `json:"event_type" gorm:"not null"`
+	Payload        string         `json:"payload" gorm:"not null"`
+	Attempt        int            `json:"attempt" gorm:"not null"`
+	Status         DeliveryStatus `json:"status" gorm:"not null"`
+	ResponseStatus int            `json:"response_status,omitempty"`
+	ResponseBody   string         `json:"response_body,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	DurationMs     int64          `json:"duration_ms"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// WebhookJob is a delivery waiting for its next attempt. It is stored with
+// the event it delivers and removed once the delivery succeeds or gives up.
+type WebhookJob struct {
+	ID          uint64    `gorm:"primary_key"`
+	WebhookID   uint      `gorm:"not null"`
+	EventID     string    `gorm:"not null"`
+	EventType   EventType `gorm:"not null"`
+	Payload     string    `gorm:"not null"`
+	Attempt     int       `gorm:"not null"`
+	AvailableAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	CreatedAt   time.Time
+}
+
+// EventTypeList is stored as a JSON array so subscriptions can be filtered with @>.
+type EventTypeList []EventType
+
+func (l EventTypeList) Value() (driver.Value, error) {
+	if l == nil {
+		l = EventTypeList{}
+	}
+	b, err := json.Marshal([]EventType(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *EventTypeList) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*l = nil
+		return nil
+	default:
+		return errors.New("unsupported type for EventTypeList")
+	}
+	return json.Unmarshal(b, (*[]EventType)(l))
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"sync"
+)
+
+type Handler func(ctx context.Context, event domain.Event) error
+
+// Bus fans events relayed from the outbox out to in-process handlers.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Handle(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish calls every handler and joins their errors. A failed publish is
+// retried by the relay, so handlers must tolerate duplicates.
+func (b *Bus) Publish(ctx context.Context, event domain.Event) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"chats/internal/domain"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Begin(Header) error {
+	return e.w.Write([]string{"seq", "id", "created_at", "edited_at", "author", "text"})
+}
+
+func (e *csvEncoder) Message(message domain.Message) error {
+	var editedAt string
+	if message.EditedAt != nil {
+		editedAt = formatTime(*message.EditedAt)
+	}
+
+	return e.w.Write([]string{
+		strconv.FormatUint(message.Seq, 10),
+		strconv.FormatUint(uint64(message.ID), 10),
+		formatTime(message.CreatedAt),
+		editedAt,
+		cell(message.Author),
+		cell(message.Text),
+	})
+}
+
+// cell keeps spreadsheets from evaluating user text as a formula.
+func cell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
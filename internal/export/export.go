@@ -0,0 +1,65 @@
+// Package export renders chat transcripts. Encoders write one message at a
+// time, so a transcript of any length is produced in constant memory.
+package export
+
+import (
+	"chats/internal/domain"
+	"io"
+	"slices"
+	"time"
+)
+
+// Header describes the transcript being written.
+type Header struct {
+	Chat *domain.Chat
+	// From and To echo the requested created_at range; zero means open.
+	From       time.Time
+	To         time.Time
+	ExportedAt time.Time
+}
+
+// Encoder writes a transcript: Begin once, Message for every message in
+// order, then End. Encoders may buffer; End flushes what they hold.
+type Encoder interface {
+	Begin(header Header) error
+	Message(message domain.Message) error
+	End() error
+}
+
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	New         func(w io.Writer) Encoder
+}
+
+var formats = map[string]Format{
+	"json":   {Name: "json", ContentType: "application/json", Extension: "json", New: newJSONEncoder},
+	"ndjson": {Name: "ndjson", ContentType: "application/x-ndjson", Extension: "ndjson", New: newNDJSONEncoder},
+	"csv":    {Name: "csv", ContentType: "text/csv; charset=utf-8", Extension: "csv", New: newCSVEncoder},
+	"md":     {Name: "md", ContentType: "text/markdown; charset=utf-8", Extension: "md", New: newMarkdownEncoder},
+	"html":   {Name: "html", ContentType: "text/html; charset=utf-8", Extension: "html", New: newHTMLEncoder},
+}
+
+func Lookup(name string) (Format, bool) {
+	format, ok := formats[name]
+	return format, ok
+}
+
+// Names lists the supported formats in a stable order.
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatReadableTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"bytes"
+	"chats/internal/domain"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	editedAt   = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	testHeader = Header{
+		Chat:       &domain.Chat{ID: 7, Title: "Release <planning>", LastSeq: 2, CreatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		From:       time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		ExportedAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	}
+	testMessages = []domain.Message{
+		{ID: 10, ChatID: 7, Seq: 1, Author: "ann", Text: "ship it?", CreatedAt: time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)},
+		{ID: 11, ChatID: 7, Seq: 2, EditedAt: &editedAt, Text: "<script>alert(1)</script>\nsecond line, \"quoted\"", CreatedAt: time.Date(2026, 3, 2, 9, 31, 0, 0, time.UTC)},
+	}
+)
+
+func render(t *testing.T, name string, messages []domain.Message) string {
+	t.Helper()
+
+	format, ok := Lookup(name)
+	require.True(t, ok)
+
+	var buf bytes.Buffer
+	enc := format.New(&buf)
+	require.NoError(t, enc.Begin(testHeader))
+	for _, message := range messages {
+		require.NoError(t, enc.Message(message))
+	}
+	require.NoError(t, enc.End())
+	return buf.String()
+}
+
+func TestJSON(t *testing.T) {
+	for _, messages := range [][]domain.Message{testMessages, nil} {
+		var out struct {
+			Chat     domain.Chat      `json:"chat"`
+			From     *time.Time       `json:"from"`
+			To       *time.Time       `json:"to"`
+			Messages []domain.Message `json:"messages"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(render(t, "json", messages)), &out))
+
+		assert.Equal(t, "Release <planning>", out.Chat.Title)
+		assert.Equal(t, testHeader.From, *out.From)
+		assert.Nil(t, out.To)
+		assert.Len(t, out.Messages, len(messages))
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(render(t, "ndjson", testMessages)), "\n")
+	require.Len(t, lines, 2)
+
+	var first domain.Message
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, testMessages[0], first)
+}
+
+func TestCSV(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(render(t, "csv", testMessages))).ReadAll()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"seq", "id", "created_at", "edited_at", "author", "text"}, records[0])
+	assert.Equal(t, []string{"1", "10", "2026-03-02T09:30:00Z", "", "ann", "ship it?"}, records[1])
+	assert.Equal(t, "2026-03-02T10:00:00Z", records[2][3])
+	assert.Equal(t, testMessages[1].Text, records[2][5])
+}
+
+func TestCSV_EscapesFormulas(t *testing.T) {
+	messages := []domain.Message{
+		{Seq: 1, Author: "@admin", Text: `=HYPERLINK("https://evil.example","click")`},
+		{Seq: 2, Author: "bob", Text: "-1 from me"},
+		{Seq: 3, Author: "ann", Text: "a = b"},
+	}
+	records, err := csv.NewReader(strings.NewReader(render(t, "csv", messages))).ReadAll()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"'@admin", `'=HYPERLINK("https://evil.example","click")`}, records[1][4:])
+	assert.Equal(t, "'-1 from me", records[2][5])
+	assert.Equal(t, "a = b", records[3][5])
+}
+
+func TestMarkdown(t *testing.T) {
+	out := render(t, "md", testMessages)
+
+	assert.True(t, strings.HasPrefix(out, "# Release \\<planning\\>\n"))
+	assert.Contains(t, out, "Chat 7 · messages from 2026-03-01 00:00:00 UTC · exported 2026-10-19 12:00:00 UTC")
+	assert.Contains(t, out, "**ann** · 2026-03-02 09:30:00 UTC · #1\n\n> ship it?  \n")
+	assert.Contains(t, out, "**unknown** · 2026-03-02 09:31:00 UTC · edited 2026-03-02 10:00:00 UTC · #2")
+	assert.Contains(t, out, "> second line, \"quoted\"  \n")
+}
+
+func TestHTML(t *testing.T) {
+	out := render(t, "html", testMessages)
+
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.True(t, strings.HasSuffix(out, "</html>\n"))
+	assert.Contains(t, out, "<title>Release &lt;planning&gt;</title>")
+	assert.Contains(t, out, `<span class="author">ann</span>`)
+	assert.Contains(t, out, `<time class="edited" datetime="2026-03-02T10:00:00Z"`)
+	assert.NotContains(t, out, "<script>")
+	assert.Contains(t, out, "&lt;script&gt;alert(1)&lt;/script&gt;")
+}
+
+func TestLookup(t *testing.T) {
+	_, ok := Lookup("pdf")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"csv", "html", "json", "md", "ndjson"}, Names())
+}
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"chats/internal/domain"
+	"html/template"
+	"io"
+)
+
+// The page carries its own styles and no scripts, so it can be archived and
+// opened offline as a single file.
+var htmlTemplates = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time":     formatReadableTime,
+	"datetime": formatTime,
+	"describe": describe,
+}).Parse(`
+{{- define "begin" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Chat.Title}}</title>
+<style>
+  body { font: 15px/1.5 -apple-system, "Segoe UI", Roboto, sans-serif; color: #1f2328; background: #f6f8fa; margin: 0; }
+  main { max-width: 46rem; margin: 0 auto; padding: 2rem 1rem; }
+  h1 { margin: 0 0 .25rem; font-size: 1.6rem; }
+  .meta { color: #59636e; font-size: .85rem; margin: 0 0 1.5rem; }
+  article { background: #fff; border: 1px solid #d1d9e0; border-radius: 6px; padding: .6rem .9rem; margin: 0 0 .6rem; }
+  header { display: flex; gap: .5rem; align-items: baseline; font-size: .85rem; color: #59636e; }
+  .author { font-weight: 600; color: #1f2328; }
+  .edited { font-style: italic; }
+  .seq { margin-left: auto; }
+  .text { white-space: pre-wrap; overflow-wrap: anywhere; margin: .3rem 0 0; }
+  @media print { body { background: #fff; } article { break-inside: avoid; } }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Chat.Title}}</h1>
+<p class="meta">{{describe .}}</p>
+{{end}}
+
+{{- define "message"}}
+<article id="m{{.Seq}}">
+<header><span class="author">{{if .Author}}{{.Author}}{{else}}unknown{{end}}</span><time datetime="{{datetime .CreatedAt}}">{{time .CreatedAt}}</time>{{with .EditedAt}}<time class="edited" datetime="{{datetime .}}" title="{{time .}}">edited</time>{{end}}<a class="seq" href="#m{{.Seq}}">#{{.Seq}}</a></header>
+<p class="text">{{.Text}}</p>
+</article>
+{{- end}}
+
+{{- define "end"}}
+</main>
+</body>
+</html>
+{{end}}`))
+
+type htmlEncoder struct {
+	w io.Writer
+}
+
+func newHTMLEncoder(w io.Writer) Encoder {
+	return &htmlEncoder{w: w}
+}
+
+func (e *htmlEncoder) Begin(header Header) error {
+	return htmlTemplates.ExecuteTemplate(e.w, "begin", header)
+}
+
+func (e *htmlEncoder) Message(message domain.Message) error {
+	return htmlTemplates.ExecuteTemplate(e.w, "message", message)
+}
+
+func (e *htmlEncoder) End() error {
+	return htmlTemplates.ExecuteTemplate(e.w, "end", nil)
+}
@@ -0,0 +1,87 @@
+package export
+
+import (
+	"chats/internal/domain"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// jsonEncoder writes {"chat": ..., "messages": [...]} without holding the
+// array: the envelope is written around the messages by hand.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) Encoder {
+	return &jsonEncoder{w: w}
+}
+
+type jsonHeader struct {
+	Chat       *domain.Chat `json:"chat"`
+	From       *time.Time   `json:"from,omitempty"`
+	To         *time.Time   `json:"to,omitempty"`
+	ExportedAt time.Time    `json:"exported_at"`
+}
+
+func (e *jsonEncoder) Begin(header Header) error {
+	head := jsonHeader{Chat: header.Chat, ExportedAt: header.ExportedAt.UTC()}
+	if !header.From.IsZero() {
+		head.From = &header.From
+	}
+	if !header.To.IsZero() {
+		head.To = &header.To
+	}
+
+	data, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	// Reopen the object to append the messages array.
+	data = append(data[:len(data)-1], `,"messages":[`...)
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) Message(message domain.Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "\n"
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) End() error {
+	_, err := io.WriteString(e.w, "\n]}\n")
+	return err
+}
+
+// ndjsonEncoder writes one message per line, in the shape accepted by the
+// message import endpoint.
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) Encoder {
+	return &ndjsonEncoder{enc: json.NewEncoder(w)}
+}
+
+func (e *ndjsonEncoder) Begin(Header) error { return nil }
+
+func (e *ndjsonEncoder) Message(message domain.Message) error {
+	return e.enc.Encode(message)
+}
+
+func (e *ndjsonEncoder) End() error { return nil }
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"chats/internal/domain"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markdownEscaper keeps titles and authors from being read as markup.
+// Message text is quoted as written.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`,
+)
+
+type markdownEncoder struct {
+	w io.Writer
+}
+
+func newMarkdownEncoder(w io.Writer) Encoder {
+	return &markdownEncoder{w: w}
+}
+
+func (e *markdownEncoder) Begin(header Header) error {
+	_, err := fmt.Fprintf(e.w, "# %s\n\n%s\n\n---\n\n",
+		markdownEscaper.Replace(header.Chat.Title), describe(header))
+	return err
+}
+
+func (e *markdownEncoder) Message(message domain.Message) error {
+	author := "unknown"
+	if message.Author != "" {
+		author = markdownEscaper.Replace(message.Author)
+	}
+
+	var quoted strings.Builder
+	for line := range strings.Lines(message.Text) {
+		quoted.WriteString("> ")
+		quoted.WriteString(strings.TrimRight(line, "\r\n"))
+		quoted.WriteString("  \n")
+	}
+
+	var edited string
+	if message.EditedAt != nil {
+		edited = " · edited " + formatReadableTime(*message.EditedAt)
+	}
+
+	_, err := fmt.Fprintf(e.w, "**%s** · %s%s · #%d\n\n%s\n",
+		author, formatReadableTime(message.CreatedAt), edited, message.Seq, quoted.String())
+	return err
+}
+
+func (e *markdownEncoder) End() error { return nil }
+
+// describe is the line under the title of the readable formats.
+func describe(header Header) string {
+	parts := []string{fmt.Sprintf("Chat %d", header.Chat.ID)}
+	switch {
+	case !header.From.IsZero() && !header.To.IsZero():
+		parts = append(parts, fmt.Sprintf("messages from %s to %s", formatReadableTime(header.From), formatReadableTime(header.To)))
+	case !header.From.IsZero():
+		parts = append(parts, "messages from "+formatReadableTime(header.From))
+	case !header.To.IsZero():
+		parts = append(parts, "messages before "+formatReadableTime(header.To))
+	}
+	parts = append(parts, "exported "+formatReadableTime(header.ExportedAt))
+	return strings.Join(parts, " · ")
+}
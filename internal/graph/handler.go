@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"chats/internal/config"
+	"chats/internal/domain"
+	"chats/internal/realtime"
+	"chats/internal/services"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// NewHandler serves the GraphQL schema over HTTP and, for subscriptions,
+// over websockets (graphql-ws and graphql-transport-ws).
+func NewHandler(chatService services.ChatService, messageService services.MessageService, hub *realtime.Hub, cfg config.GraphQLConfig) http.Handler {
+	c := Config{Resolvers: &Resolver{chats: chatService, messages: messageService, hub: hub}}
+	c.Complexity.Chat.Messages = func(childComplexity int, limit *int, _ *uint64, _ *uint64) int {
+		return 1 + clampLimit(limit)*childComplexity
+	}
+
+	srv := handler.New(NewExecutableSchema(c))
+	srv.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(cfg.MaxComplexity))
+	srv.Use(DepthLimit(cfg.MaxDepth))
+
+	srv.AroundOperations(func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		return next(withLoaders(ctx, newLoaders(chatService, messageService)))
+	})
+	srv.SetErrorPresenter(presentError)
+
+	return srv
+}
+
+// presentError maps domain errors to client-facing messages. Unknown errors
+// are logged and reported without their message so database details do not
+// leak to clients.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var own *gqlerror.Error
+	if errors.As(err, &own) && own.Err == nil {
+		return gqlErr
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		gqlErr.Message = "not found"
+		errcode.Set(gqlErr, "NOT_FOUND")
+	case errors.Is(err, domain.ErrInvalidInput):
+		gqlErr.Message = domain.Describe(err, domain.ErrInvalidInput)
+		errcode.Set(gqlErr, "BAD_USER_INPUT")
+	case errors.Is(err, domain.ErrUnavailable):
+		gqlErr.Message = "database unavailable"
+		errcode.Set(gqlErr, "UNAVAILABLE")
+	case errors.Is(err, domain.ErrTimeout):
+		gqlErr.Message = "database timed out"
+		errcode.Set(gqlErr, "TIMEOUT")
+	default:
+		slog.Default().Error("GraphQL internal error", "error", err)
+		gqlErr.Message = "internal error"
+		errcode.Set(gqlErr, "INTERNAL")
+	}
+	return gqlErr
+}
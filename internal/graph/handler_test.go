@@ -0,0 +1,165 @@
+package graph
+
+import (
+	"bytes"
+	"chats/internal/config"
+	"chats/internal/domain"
+	"chats/internal/realtime"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChatService struct {
+	chats    map[uint]domain.Chat
+	getChats atomic.Int32
+}
+
+func (f *fakeChatService) CreateChat(context.Context, string) (*domain.Chat, error) {
+	return nil, errors.New("pq: connection reset by peer")
+}
+
+func (f *fakeChatService) GetChat(context.Context, uint, int) (*domain.Chat, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeChatService) GetChatState(context.Context, uint) (*domain.Chat, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeChatService) GetChats(_ context.Context, ids []uint) ([]domain.Chat, error) {
+	f.getChats.Add(1)
+	var result []domain.Chat
+	for _, id := range ids {
+		if chat, ok := f.chats[id]; ok {
+			result = append(result, chat)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeChatService) UpdateChat(context.Context, uint, string, uint64) (*domain.Chat, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeChatService) DeleteChat(context.Context, uint, uint64) error { return nil }
+
+func (f *fakeChatService) ValidateChatExists(context.Context, uint) error { return nil }
+
+type fakeMessageService struct {
+	listLatest atomic.Int32
+}
+
+func (f *fakeMessageService) CreateMessage(context.Context, uint, string) (*domain.Message, error) {
+	return nil, domain.ErrInvalidInput
+}
+
+func (f *fakeMessageService) UpdateMessage(context.Context, uint, uint64, string, uint64) (*domain.Message, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeMessageService) DeleteMessage(context.Context, uint, uint64, uint64) error {
+	return domain.ErrNotFound
+}
+
+func (f *fakeMessageService) ListMessages(context.Context, uint, domain.MessageCursor) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func (f *fakeMessageService) ListLatestMessages(_ context.Context, chatIDs []uint, _ int) (map[uint][]domain.Message, error) {
+	f.listLatest.Add(1)
+	result := make(map[uint][]domain.Message, len(chatIDs))
+	for _, id := range chatIDs {
+		result[id] = []domain.Message{{ID: id * 10, ChatID: id, Seq: 1, Text: "hi"}}
+	}
+	return result, nil
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newTestHandler(chats *fakeChatService, messages *fakeMessageService) http.Handler {
+	hub := realtime.NewHub(messages, time.Hour)
+	return NewHandler(chats, messages, hub, config.GraphQLConfig{MaxDepth: 4, MaxComplexity: 1000})
+}
+
+func execute(t *testing.T, h http.Handler, query string) gqlResponse {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]string{"query": query})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	var resp gqlResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), rr.Body.String())
+	return resp
+}
+
+func TestHandler_BatchesNestedLookups(t *testing.T) {
+	chats := &fakeChatService{chats: map[uint]domain.Chat{
+		1: {ID: 1, Title: "one"},
+		2: {ID: 2, Title: "two"},
+	}}
+	messages := &fakeMessageService{}
+	h := newTestHandler(chats, messages)
+
+	resp := execute(t, h, `{ chats(ids: [1, 2, 3]) { title messages(limit: 5) { text chat { title } } } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"chats": [
+		{"title": "one", "messages": [{"text": "hi", "chat": {"title": "one"}}]},
+		{"title": "two", "messages": [{"text": "hi", "chat": {"title": "two"}}]}
+	]}`, string(resp.Data))
+
+	assert.Equal(t, int32(1), chats.getChats.Load())
+	assert.Equal(t, int32(1), messages.listLatest.Load())
+}
+
+func TestHandler_RejectsDeepQueries(t *testing.T) {
+	h := newTestHandler(&fakeChatService{}, &fakeMessageService{})
+
+	resp := execute(t, h, `{ chat(id: 1) { messages { chat { messages { chat { title } } } } } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "DEPTH_LIMIT_EXCEEDED", resp.Errors[0].Extensions["code"])
+}
+
+func TestHandler_RejectsComplexQueries(t *testing.T) {
+	h := newTestHandler(&fakeChatService{}, &fakeMessageService{})
+
+	resp := execute(t, h, `{ chats(ids: [1]) { messages(limit: 100) { chat { messages(limit: 100) { id } } } } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "COMPLEXITY_LIMIT_EXCEEDED", resp.Errors[0].Extensions["code"])
+}
+
+func TestHandler_MapsDomainErrors(t *testing.T) {
+	h := newTestHandler(&fakeChatService{}, &fakeMessageService{})
+
+	resp := execute(t, h, `{ chat(id: 42) { title } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"chat": null}`, string(resp.Data))
+
+	resp = execute(t, h, `mutation { createMessage(chatId: 1, text: "") { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "BAD_USER_INPUT", resp.Errors[0].Extensions["code"])
+
+	resp = execute(t, h, `mutation { createChat(title: "x") { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "internal error", resp.Errors[0].Message)
+	assert.Equal(t, "INTERNAL", resp.Errors[0].Extensions["code"])
+}
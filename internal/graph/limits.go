@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// DepthLimit rejects operations nested deeper than the limit. Introspection
+// fields are not counted so tooling keeps working.
+type DepthLimit int
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit(0)
+
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(_ context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if rc.Operation == nil {
+		return nil
+	}
+
+	depth := selectionDepth(rc.Operation.SelectionSet, rc.Doc.Fragments, map[string]bool{})
+	if depth > int(d) {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, int(d))
+		errcode.Set(err, "DEPTH_LIMIT_EXCEEDED")
+		return err
+	}
+	return nil
+}
+
+func selectionDepth(set ast.SelectionSet, fragments ast.FragmentDefinitionList, visiting map[string]bool) int {
+	deepest := 0
+	for _, selection := range set {
+		var depth int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			depth = 1 + selectionDepth(s.SelectionSet, fragments, visiting)
+		case *ast.InlineFragment:
+			depth = selectionDepth(s.SelectionSet, fragments, visiting)
+		case *ast.FragmentSpread:
+			fragment := fragments.ForName(s.Name)
+			if fragment == nil || visiting[s.Name] {
+				continue
+			}
+			visiting[s.Name] = true
+			depth = selectionDepth(fragment.SelectionSet, fragments, visiting)
+			delete(visiting, s.Name)
+		}
+		deepest = max(deepest, depth)
+	}
+	return deepest
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"chats/internal/domain"
+	"chats/internal/services"
+	"context"
+	"time"
+
+	"github.com/vikstrous/dataloadgen"
+)
+
+const loaderWait = 2 * time.Millisecond
+
+type loadersKey struct{}
+
+type latestMessagesKey struct {
+	chatID uint
+	limit  int
+}
+
+// loaders batch the lookups nested fields make through the services, so a
+// query touching N chats issues one chat query and one message query
+// instead of N of each.
+type loaders struct {
+	chats          *dataloadgen.Loader[uint, *domain.Chat]
+	latestMessages *dataloadgen.Loader[latestMessagesKey, []domain.Message]
+}
+
+func newLoaders(chatService services.ChatService, messageService services.MessageService) *loaders {
+	return &loaders{
+		chats: dataloadgen.NewLoader(func(ctx context.Context, ids []uint) ([]*domain.Chat, []error) {
+			chats, err := chatService.GetChats(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+
+			byID := make(map[uint]*domain.Chat, len(chats))
+			for i := range chats {
+				byID[chats[i].ID] = &chats[i]
+			}
+
+			result := make([]*domain.Chat, len(ids))
+			errs := make([]error, len(ids))
+			for i, id := range ids {
+				if result[i] = byID[id]; result[i] == nil {
+					errs[i] = domain.ErrNotFound
+				}
+			}
+			return result, errs
+		}, dataloadgen.WithWait(loaderWait)),
+
+		latestMessages: dataloadgen.NewLoader(func(ctx context.Context, keys []latestMessagesKey) ([][]domain.Message, []error) {
+			byLimit := make(map[int][]uint)
+			for _, key := range keys {
+				byLimit[key.limit] = append(byLimit[key.limit], key.chatID)
+			}
+
+			found := make(map[latestMessagesKey][]domain.Message, len(keys))
+			for limit, chatIDs := range byLimit {
+				messages, err := messageService.ListLatestMessages(ctx, chatIDs, limit)
+				if err != nil {
+					return nil, []error{err}
+				}
+				for chatID, list := range messages {
+					found[latestMessagesKey{chatID: chatID, limit: limit}] = list
+				}
+			}
+
+			result := make([][]domain.Message, len(keys))
+			for i, key := range keys {
+				result[i] = found[key]
+			}
+			return result, nil
+		}, dataloadgen.WithWait(loaderWait)),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
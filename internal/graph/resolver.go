@@ -0,0 +1,34 @@
+package graph
+
+//go:generate sh -c "cd ../.. && go tool gqlgen generate"
+
+import (
+	"chats/internal/domain"
+	"chats/internal/limits"
+	"chats/internal/realtime"
+	"chats/internal/services"
+)
+
+// maxIDs caps the IDs of one chats query.
+const maxIDs = 100
+
+type Resolver struct {
+	chats    services.ChatService
+	messages services.MessageService
+	hub      *realtime.Hub
+}
+
+func clampLimit(limit *int) int {
+	if limit == nil {
+		return limits.Current().DefaultHistory
+	}
+	return limits.Current().ClampHistory(*limit)
+}
+
+func toPointers(messages []domain.Message) []*domain.Message {
+	result := make([]*domain.Message, len(messages))
+	for i := range messages {
+		result[i] = &messages[i]
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen
+
+import (
+	"chats/internal/domain"
+	"chats/internal/realtime"
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/vikstrous/dataloadgen"
+)
+
+// Messages is the resolver for the messages field.
+func (r *chatResolver) Messages(ctx context.Context, obj *domain.Chat, limit *int, afterSeq *uint64, beforeSeq *uint64) ([]*domain.Message, error) {
+	n := clampLimit(limit)
+
+	if afterSeq == nil && beforeSeq == nil {
+		messages, err := loadersFrom(ctx).latestMessages.Load(ctx, latestMessagesKey{chatID: obj.ID, limit: n})
+		if err != nil {
+			return nil, err
+		}
+		return toPointers(messages), nil
+	}
+
+	cursor := domain.MessageCursor{Limit: n}
+	if afterSeq != nil {
+		cursor.AfterSeq = *afterSeq
+	}
+	if beforeSeq != nil {
+		cursor.BeforeSeq = *beforeSeq
+	}
+
+	messages, err := r.messages.ListMessages(ctx, obj.ID, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return toPointers(messages), nil
+}
+
+// Chat is the resolver for the chat field.
+func (r *messageResolver) Chat(ctx context.Context, obj *domain.Message) (*domain.Chat, error) {
+	return loadersFrom(ctx).chats.Load(ctx, obj.ChatID)
+}
+
+// CreateChat is the resolver for the createChat field.
+func (r *mutationResolver) CreateChat(ctx context.Context, title string) (*domain.Chat, error) {
+	return r.chats.CreateChat(ctx, title)
+}
+
+// DeleteChat is the resolver for the deleteChat field.
+func (r *mutationResolver) DeleteChat(ctx context.Context, id uint) (bool, error) {
+	if err := r.chats.DeleteChat(ctx, id, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateMessage is the resolver for the createMessage field.
+func (r *mutationResolver) CreateMessage(ctx context.Context, chatID uint, text string) (*domain.Message, error) {
+	return r.messages.CreateMessage(ctx, chatID, text)
+}
+
+// Chat is the resolver for the chat field.
+func (r *queryResolver) Chat(ctx context.Context, id uint) (*domain.Chat, error) {
+	chat, err := loadersFrom(ctx).chats.Load(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	return chat, err
+}
+
+// Chats is the resolver for the chats field.
+func (r *queryResolver) Chats(ctx context.Context, ids []uint) ([]*domain.Chat, error) {
+	if len(ids) > maxIDs {
+		return nil, domain.ErrInvalidInput
+	}
+
+	chats, err := loadersFrom(ctx).chats.LoadAll(ctx, ids)
+	var errs dataloadgen.ErrorSlice
+	if err != nil && !errors.As(err, &errs) {
+		return nil, err
+	}
+
+	result := make([]*domain.Chat, 0, len(chats))
+	for i, chat := range chats {
+		if i < len(errs) && errs[i] != nil {
+			// Unknown IDs are skipped rather than failing the whole list.
+			if errors.Is(errs[i], domain.ErrNotFound) {
+				continue
+			}
+			return nil, errs[i]
+		}
+		result = append(result, chat)
+	}
+	return result, nil
+}
+
+// MessageAdded is the resolver for the messageAdded field.
+func (r *subscriptionResolver) MessageAdded(ctx context.Context, chatID uint, afterSeq *uint64) (<-chan *domain.Message, error) {
+	if err := r.chats.ValidateChatExists(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	var after uint64
+	if afterSeq != nil {
+		after = *afterSeq
+	}
+
+	ch := make(chan *domain.Message)
+	go func() {
+		defer close(ch)
+		err := r.hub.Stream(ctx, chatID, after, func(message domain.Message) error {
+			select {
+			case ch <- &message:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && ctx.Err() == nil && !errors.Is(err, realtime.ErrClosed) {
+			slog.Default().Warn("GraphQL subscription ended", "chat_id", chatID, "error", err)
+		}
+	}()
+	return ch, nil
+}
+
+// Chat returns ChatResolver implementation.
+func (r *Resolver) Chat() ChatResolver { return &chatResolver{r} }
+
+// Message returns MessageResolver implementation.
+func (r *Resolver) Message() MessageResolver { return &messageResolver{r} }
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+type chatResolver struct{ *Resolver }
+type messageResolver struct{ *Resolver }
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
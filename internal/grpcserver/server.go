@@ -0,0 +1,230 @@
+package grpcserver
+
+//go:generate sh -c "cd ../../api && buf generate"
+
+import (
+	chatsv1 "chats/api/chats/v1"
+	"chats/internal/domain"
+	"chats/internal/limits"
+	"chats/internal/middleware"
+	"chats/internal/protoconv"
+	"chats/internal/realtime"
+	"chats/internal/services"
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer exposes the chat and message services over gRPC together with
+// the standard health and reflection services. opts are applied after the
+// built-in interceptors.
+func NewServer(chatService services.ChatService, messageService services.MessageService, hub *realtime.Hub, opts ...grpc.ServerOption) (*grpc.Server, *health.Server) {
+	server := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(recoverUnary),
+		grpc.ChainStreamInterceptor(recoverStream),
+	}, opts...)...)
+
+	chatsv1.RegisterChatServiceServer(server, &chatServer{chats: chatService, hub: hub})
+	chatsv1.RegisterMessageServiceServer(server, &messageServer{messages: messageService})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(chatsv1.ChatService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(chatsv1.MessageService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server, healthServer
+}
+
+type chatServer struct {
+	chatsv1.UnimplementedChatServiceServer
+	chats services.ChatService
+	hub   *realtime.Hub
+}
+
+func (s *chatServer) CreateChat(ctx context.Context, req *chatsv1.CreateChatRequest) (*chatsv1.CreateChatResponse, error) {
+	chat, err := s.chats.CreateChat(ctx, req.GetTitle())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chatsv1.CreateChatResponse{Chat: protoconv.Chat(chat)}, nil
+}
+
+func (s *chatServer) GetChat(ctx context.Context, req *chatsv1.GetChatRequest) (*chatsv1.GetChatResponse, error) {
+	id, err := toID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := s.chats.GetChat(ctx, id, clampLimit(req.GetLimit()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chatsv1.GetChatResponse{Chat: protoconv.Chat(chat)}, nil
+}
+
+func (s *chatServer) DeleteChat(ctx context.Context, req *chatsv1.DeleteChatRequest) (*chatsv1.DeleteChatResponse, error) {
+	id, err := toID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.chats.DeleteChat(ctx, id, 0); err != nil {
+		return nil, toStatus(err)
+	}
+	return &chatsv1.DeleteChatResponse{}, nil
+}
+
+func (s *chatServer) SubscribeChat(req *chatsv1.SubscribeChatRequest, stream grpc.ServerStreamingServer[chatsv1.SubscribeChatResponse]) error {
+	id, err := toID(req.GetChatId())
+	if err != nil {
+		return err
+	}
+
+	err = s.hub.Stream(stream.Context(), id, req.GetAfterSeq(), func(message domain.Message) error {
+		return stream.Send(&chatsv1.SubscribeChatResponse{Message: protoconv.Message(&message)})
+	})
+	return toStatus(err)
+}
+
+type messageServer struct {
+	chatsv1.UnimplementedMessageServiceServer
+	messages services.MessageService
+}
+
+func (s *messageServer) CreateMessage(ctx context.Context, req *chatsv1.CreateMessageRequest) (*chatsv1.CreateMessageResponse, error) {
+	chatID, err := toID(req.GetChatId())
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.messages.CreateMessage(ctx, chatID, req.GetText())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chatsv1.CreateMessageResponse{Message: protoconv.Message(message)}, nil
+}
+
+func (s *messageServer) ListMessages(ctx context.Context, req *chatsv1.ListMessagesRequest) (*chatsv1.ListMessagesResponse, error) {
+	chatID, err := toID(req.GetChatId())
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.messages.ListMessages(ctx, chatID, domain.MessageCursor{
+		AfterSeq:  req.GetAfterSeq(),
+		BeforeSeq: req.GetBeforeSeq(),
+		Limit:     clampLimit(req.GetLimit()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &chatsv1.ListMessagesResponse{Messages: protoconv.Messages(messages)}, nil
+}
+
+// toStatus maps domain errors to gRPC codes. Unknown errors become Internal
+// without their message so database details do not leak to clients.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, realtime.ErrClosed):
+		return status.Error(codes.Unavailable, "server shutting down, resubscribe from the last seq")
+	case errors.Is(err, domain.ErrInvalidInput):
+		return status.Error(codes.InvalidArgument, domain.Describe(err, domain.ErrInvalidInput))
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, "already exists")
+	case errors.Is(err, domain.ErrUnavailable):
+		return status.Error(codes.Unavailable, "database unavailable")
+	case errors.Is(err, domain.ErrTimeout):
+		return status.Error(codes.DeadlineExceeded, "database timed out")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	default:
+		slog.Default().Error("gRPC internal error", "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toID(id uint64) (uint, error) {
+	if id == 0 || id > uint64(^uint(0)) {
+		return 0, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	return uint(id), nil
+}
+
+func clampLimit(limit int32) int {
+	return limits.Current().ClampHistory(int(limit))
+}
+
+// Deadline gives each unary call at most timeout, unless the client asked
+// for less. Streams are left alone. Zero disables the deadline.
+func Deadline(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// ReadYourWrites keeps callers of unary methods on the primary database
+// while s says so. Callers are identified as over HTTP, by their
+// authorization metadata or else the peer address, so s can be shared with
+// the HTTP API.
+func ReadYourWrites(s *middleware.Stickiness) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var auth, addr string
+		if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+			auth = values[0]
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		return handler(s.Session(ctx, middleware.CallerIDFrom(auth, addr)), req)
+	}
+}
+
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Default().Error("gRPC panic", "method", info.FullMethod, "panic", p, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Default().Error("gRPC panic", "method", info.FullMethod, "panic", p, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(srv, ss)
+}
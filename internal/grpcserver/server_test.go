@@ -0,0 +1,224 @@
+package grpcserver
+
+import (
+	chatsv1 "chats/api/chats/v1"
+	"chats/internal/domain"
+	"chats/internal/realtime"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeChatService struct {
+	chats map[uint]*domain.Chat
+}
+
+func (f *fakeChatService) CreateChat(_ context.Context, title string) (*domain.Chat, error) {
+	if title == "" {
+		return nil, domain.ErrInvalidInput
+	}
+	chat := &domain.Chat{ID: uint(len(f.chats) + 1), Title: title}
+	f.chats[chat.ID] = chat
+	return chat, nil
+}
+
+func (f *fakeChatService) GetChat(_ context.Context, id uint, _ int) (*domain.Chat, error) {
+	if chat, ok := f.chats[id]; ok {
+		return chat, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeChatService) GetChatState(ctx context.Context, id uint) (*domain.Chat, error) {
+	return f.GetChat(ctx, id, 0)
+}
+
+func (f *fakeChatService) GetChats(context.Context, []uint) ([]domain.Chat, error) {
+	return nil, nil
+}
+
+func (f *fakeChatService) UpdateChat(context.Context, uint, string, uint64) (*domain.Chat, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeChatService) DeleteChat(context.Context, uint, uint64) error {
+	return errors.New("pq: connection reset by peer")
+}
+
+func (f *fakeChatService) ValidateChatExists(_ context.Context, id uint) error {
+	if _, ok := f.chats[id]; ok {
+		return nil
+	}
+	return domain.ErrNotFound
+}
+
+type fakeMessageService struct {
+	mu       sync.Mutex
+	messages []domain.Message
+}
+
+func (f *fakeMessageService) CreateMessage(_ context.Context, chatID uint, text string) (*domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	message := domain.Message{ID: uint(len(f.messages) + 1), ChatID: chatID, Seq: uint64(len(f.messages) + 1), Text: text}
+	f.messages = append(f.messages, message)
+	return &message, nil
+}
+
+func (f *fakeMessageService) UpdateMessage(context.Context, uint, uint64, string, uint64) (*domain.Message, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeMessageService) DeleteMessage(context.Context, uint, uint64, uint64) error {
+	return domain.ErrNotFound
+}
+
+func (f *fakeMessageService) ListMessages(_ context.Context, _ uint, cursor domain.MessageCursor) ([]domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var page []domain.Message
+	for _, m := range f.messages {
+		if m.Seq > cursor.AfterSeq && len(page) < cursor.Limit {
+			page = append(page, m)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeMessageService) ListLatestMessages(context.Context, []uint, int) (map[uint][]domain.Message, error) {
+	return nil, nil
+}
+
+func startServer(t *testing.T) (*grpc.ClientConn, *fakeMessageService, *realtime.Hub) {
+	t.Helper()
+
+	chats := &fakeChatService{chats: map[uint]*domain.Chat{1: {ID: 1, Title: "general"}}}
+	messages := &fakeMessageService{}
+	hub := realtime.NewHub(messages, time.Hour)
+	server, _ := NewServer(chats, messages, hub)
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn, messages, hub
+}
+
+func TestServer_MapsDomainErrorsToCodes(t *testing.T) {
+	conn, _, _ := startServer(t)
+	client := chatsv1.NewChatServiceClient(conn)
+	ctx := context.Background()
+
+	_, err := client.GetChat(ctx, &chatsv1.GetChatRequest{Id: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.CreateChat(ctx, &chatsv1.CreateChatRequest{Title: ""})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetChat(ctx, &chatsv1.GetChatRequest{Id: 0})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.DeleteChat(ctx, &chatsv1.DeleteChatRequest{Id: 1})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.NotContains(t, status.Convert(err).Message(), "pq:")
+
+	resp, err := client.CreateChat(ctx, &chatsv1.CreateChatRequest{Title: "new"})
+	require.NoError(t, err)
+	assert.Equal(t, "new", resp.GetChat().GetTitle())
+}
+
+func TestServer_SubscribeChatReplaysThenStreams(t *testing.T) {
+	conn, messages, hub := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, text := range []string{"one", "two", "three"} {
+		_, _ = messages.CreateMessage(ctx, 1, text)
+	}
+
+	stream, err := chatsv1.NewChatServiceClient(conn).SubscribeChat(ctx, &chatsv1.SubscribeChatRequest{ChatId: 1, AfterSeq: 1})
+	require.NoError(t, err)
+
+	for _, want := range []uint64{2, 3} {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.GetMessage().GetSeq())
+	}
+
+	created, err := chatsv1.NewMessageServiceClient(conn).CreateMessage(ctx, &chatsv1.CreateMessageRequest{ChatId: 1, Text: "four"})
+	require.NoError(t, err)
+	event, err := domain.NewEvent(domain.EventMessageCreated, 1, created.GetMessage())
+	require.NoError(t, err)
+	require.NoError(t, hub.Publish(ctx, event))
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), resp.GetMessage().GetSeq())
+	assert.Equal(t, "four", resp.GetMessage().GetText())
+}
+
+func TestServer_SubscribeChatEndsWhenHubCloses(t *testing.T) {
+	conn, _, hub := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := chatsv1.NewChatServiceClient(conn).SubscribeChat(ctx, &chatsv1.SubscribeChatRequest{ChatId: 1})
+	require.NoError(t, err)
+
+	hub.Close()
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestServer_ExposesHealth(t *testing.T) {
+	conn, _, _ := startServer(t)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: chatsv1.ChatService_ServiceDesc.ServiceName,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestDeadline(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	handler := func(ctx context.Context, req any) (any, error) {
+		deadline, ok = ctx.Deadline()
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/chats.v1.ChatService/GetChat"}
+
+	_, _ = Deadline(time.Minute)(context.Background(), nil, info, handler)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	want, _ := ctx.Deadline()
+	_, _ = Deadline(time.Minute)(ctx, nil, info, handler)
+	assert.Equal(t, want, deadline, "the client's shorter deadline wins")
+
+	_, _ = Deadline(0)(context.Background(), nil, info, handler)
+	assert.False(t, ok)
+}
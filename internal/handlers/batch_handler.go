@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"chats/internal/domain"
+	"chats/internal/limits"
+	"chats/internal/logging"
+	"chats/internal/middleware"
+	"chats/internal/problem"
+	"chats/internal/services"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// maxBatchBody bounds the request body; the operation count is capped separately.
+const maxBatchBody = 1 << 20
+
+type BatchOptions struct {
+	// MaxOperations caps the operations of one batch.
+	MaxOperations int
+	// AdminToken authorizes admin operations, like the /api/admin routes.
+	AdminToken string
+	// RequireIfMatch makes if_match mandatory on updates and deletes, like
+	// the RequireIfMatch middleware does for PATCH and DELETE.
+	RequireIfMatch bool
+}
+
+type BatchHandler struct {
+	chats    services.ChatService
+	messages services.MessageService
+	webhooks services.WebhookService
+	tx       services.Transactor
+	opts     BatchOptions
+}
+
+func NewBatchHandler(
+	chats services.ChatService,
+	messages services.MessageService,
+	webhooks services.WebhookService,
+	tx services.Transactor,
+	opts BatchOptions,
+) *BatchHandler {
+	return &BatchHandler{
+		chats:    chats,
+		messages: messages,
+		webhooks: webhooks,
+		tx:       tx,
+		opts:     opts,
+	}
+}
+
+// batchOperation is one sub-request. Fields an operation does not use are ignored.
+type batchOperation struct {
+	// ID is echoed in the result to help clients match them up.
+	ID        string `json:"id,omitempty"`
+	Op        string `json:"op"`
+	ChatID    uint   `json:"chat_id,omitempty"`
+	Seq       uint64 `json:"seq,omitempty"`
+	WebhookID uint   `json:"webhook_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+	AfterSeq  uint64 `json:"after_seq,omitempty"`
+	BeforeSeq uint64 `json:"before_seq,omitempty"`
+	IfMatch   string `json:"if_match,omitempty"`
+}
+
+type batchResult struct {
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	ETag   string `json:"etag,omitempty"`
+	Body   any    `json:"body,omitempty"`
+}
+
+type batchResponse struct {
+	// Committed is false when an atomic batch was rolled back.
+	Committed bool          `json:"committed"`
+	Results   []batchResult `json:"results"`
+}
+
+// batchOp runs one kind of operation; admin ones are authorized per item.
+type batchOp struct {
+	admin bool
+	run   func(h *BatchHandler, ctx context.Context, r *http.Request, op batchOperation) (batchResult, error)
+}
+
+var batchOps = map[string]batchOp{
+	"get_chat":       {run: (*BatchHandler).getChat},
+	"create_chat":    {run: (*BatchHandler).createChat},
+	"update_chat":    {run: (*BatchHandler).updateChat},
+	"delete_chat":    {run: (*BatchHandler).deleteChat},
+	"list_messages":  {run: (*BatchHandler).listMessages},
+	"create_message": {run: (*BatchHandler).createMessage},
+	"update_message": {run: (*BatchHandler).updateMessage},
+	"delete_message": {run: (*BatchHandler).deleteMessage},
+	"list_webhooks":  {admin: true, run: (*BatchHandler).listWebhooks},
+	"get_webhook":    {admin: true, run: (*BatchHandler).getWebhook},
+}
+
+// errBatchFailed rolls back an atomic batch once an operation has failed.
+var errBatchFailed = errors.New("batch operation failed")
+
+func (h *BatchHandler) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	var request struct {
+		Atomic     bool             `json:"atomic"`
+		Operations []batchOperation `json:"operations"`
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBody)
+	if err := decode(r, &request); err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	if len(request.Operations) == 0 {
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidInput, "operations must not be empty."))
+		return
+	}
+	if len(request.Operations) > h.opts.MaxOperations {
+		logger.Warn("Batch too large", "operations", len(request.Operations))
+		problem.Write(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge,
+			fmt.Sprintf("A batch holds at most %d operations.", h.opts.MaxOperations)))
+		return
+	}
+
+	response := batchResponse{Committed: true}
+	if request.Atomic {
+		response = h.runAtomic(r, request.Operations)
+	} else {
+		for _, op := range request.Operations {
+			result, _ := h.run(r.Context(), r, op)
+			response.Results = append(response.Results, result)
+		}
+	}
+
+	render(w, r, http.StatusOK, response)
+}
+
+// runAtomic runs ops in one transaction, stopping at the first failure.
+func (h *BatchHandler) runAtomic(r *http.Request, ops []batchOperation) batchResponse {
+	results := make([]batchResult, 0, len(ops))
+	failed := -1
+
+	err := h.tx.WithinTx(r.Context(), func(ctx context.Context) error {
+		for i, op := range ops {
+			result, ok := h.run(ctx, r, op)
+			results = append(results, result)
+			if !ok {
+				failed = i
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+
+	if err == nil {
+		return batchResponse{Committed: true, Results: results}
+	}
+
+	if failed < 0 {
+		// Every operation succeeded but the commit did not, so none of them persisted.
+		logging.FromContext(r.Context()).Error("Error committing batch", "error", err)
+		for i := range results {
+			results[i] = aborted(r, ops[i], "Rolled back because the transaction could not be committed.")
+		}
+		return batchResponse{Committed: false, Results: results}
+	}
+
+	for i := range ops {
+		switch {
+		case i < failed:
+			results[i] = aborted(r, ops[i], fmt.Sprintf("Rolled back because operation %d failed.", failed))
+		case i > failed:
+			results = append(results, aborted(r, ops[i], fmt.Sprintf("Not run because operation %d failed.", failed)))
+		}
+	}
+	return batchResponse{Committed: false, Results: results}
+}
+
+func (h *BatchHandler) run(ctx context.Context, r *http.Request, op batchOperation) (batchResult, bool) {
+	kind, ok := batchOps[op.Op]
+	if !ok {
+		return failure(r, op, problem.New(http.StatusBadRequest, problem.CodeInvalidInput, "Unknown operation "+strconv.Quote(op.Op)+".")), false
+	}
+	if kind.admin {
+		if p := middleware.CheckAdmin(r, h.opts.AdminToken); p != nil {
+			return failure(r, op, p), false
+		}
+	}
+
+	result, err := kind.run(h, ctx, r, op)
+	if err != nil {
+		return failure(r, op, problem.Resolve(r, err)), false
+	}
+	result.ID = op.ID
+	return result, true
+}
+
+func failure(r *http.Request, op batchOperation, p *problem.Problem) batchResult {
+	body := problem.Complete(r, p)
+	return batchResult{ID: op.ID, Status: body.Status, Body: body}
+}
+
+func aborted(r *http.Request, op batchOperation, detail string) batchResult {
+	return failure(r, op, problem.New(http.StatusFailedDependency, problem.CodeBatchAborted, detail))
+}
+
+// ifMatch checks if_match as the If-Match header is checked on the route.
+func (h *BatchHandler) ifMatch(op batchOperation) (uint64, error) {
+	if op.IfMatch == "" && h.opts.RequireIfMatch {
+		return 0, problem.New(http.StatusPreconditionRequired, problem.CodePreconditionRequired,
+			"Send if_match with the ETag of the version you are changing.")
+	}
+	return parseIfMatch(op.IfMatch)
+}
+
+func (h *BatchHandler) getChat(ctx context.Context, r *http.Request, op batchOperation) (batchResult, error) {
+	chat, err := h.chats.GetChat(ctx, op.ChatID, limits.Current().ClampHistory(op.Limit))
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusOK, ETag: chatETag(r, chat, batchResponse{}), Body: presentChat(r, chat)}, nil
+}
+
+func (h *BatchHandler) createChat(ctx context.Context, r *http.Request, op batchOperation) (batchResult, error) {
+	chat, err := h.chats.CreateChat(ctx, op.Title)
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusCreated, ETag: chatETag(r, chat, batchResponse{}), Body: presentChat(r, chat)}, nil
+}
+
+func (h *BatchHandler) updateChat(ctx context.Context, r *http.Request, op batchOperation) (batchResult, error) {
+	version, err := h.ifMatch(op)
+	if err != nil {
+		return batchResult{}, err
+	}
+	chat, err := h.chats.UpdateChat(ctx, op.ChatID, op.Title, version)
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusOK, ETag: chatETag(r, chat, batchResponse{}), Body: presentChat(r, chat)}, nil
+}
+
+func (h *BatchHandler) deleteChat(ctx context.Context, _ *http.Request, op batchOperation) (batchResult, error) {
+	version, err := h.ifMatch(op)
+	if err != nil {
+		return batchResult{}, err
+	}
+	if err := h.chats.DeleteChat(ctx, op.ChatID, version); err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusNoContent}, nil
+}
+
+func (h *BatchHandler) listMessages(ctx context.Context, r *http.Request, op batchOperation) (batchResult, error) {
+	cursor := domain.MessageCursor{AfterSeq: op.AfterSeq, BeforeSeq: op.BeforeSeq, Limit: limits.Current().ClampHistory(op.Limit)}
+	messages, err := h.messages.ListMessages(ctx, op.ChatID, cursor)
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusOK, Body: presentMessages(r, messages)}, nil
+}
+
+func (h *BatchHandler) createMessage(ctx context.Context, r *http.Request, op batchOperation) (batchResult, error) {
+	message, err := h.messages.CreateMessage(ctx, op.ChatID, op.Text)
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusCreated, ETag: versionETag(r, message.Version, batchResponse{}), Body: message}, nil
+}
+
+func (h *BatchHandler) updateMessage(ctx context.Context, r *http.Request, op batchOperation) (batchResult, error) {
+	version, err := h.ifMatch(op)
+	if err != nil {
+		return batchResult{}, err
+	}
+	message, err := h.messages.UpdateMessage(ctx, op.ChatID, op.Seq, op.Text, version)
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusOK, ETag: versionETag(r, message.Version, batchResponse{}), Body: message}, nil
+}
+
+func (h *BatchHandler) deleteMessage(ctx context.Context, _ *http.Request, op batchOperation) (batchResult, error) {
+	version, err := h.ifMatch(op)
+	if err != nil {
+		return batchResult{}, err
+	}
+	if err := h.messages.DeleteMessage(ctx, op.ChatID, op.Seq, version); err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusNoContent}, nil
+}
+
+func (h *BatchHandler) listWebhooks(ctx context.Context, r *http.Request, _ batchOperation) (batchResult, error) {
+	webhooks, err := h.webhooks.ListWebhooks(ctx)
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusOK, Body: presentList(r, webhooks)}, nil
+}
+
+func (h *BatchHandler) getWebhook(ctx context.Context, _ *http.Request, op batchOperation) (batchResult, error) {
+	webhook, err := h.webhooks.GetWebhook(ctx, op.WebhookID)
+	if err != nil {
+		return batchResult{}, err
+	}
+	return batchResult{Status: http.StatusOK, Body: webhook}, nil
+}
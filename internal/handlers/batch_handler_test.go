@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"chats/internal/domain"
+	"chats/internal/problem"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTransactor struct {
+	calls int
+}
+
+func (f *fakeTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.calls++
+	return fn(ctx)
+}
+
+type batchResultBody struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	ETag   string          `json:"etag"`
+	Body   json.RawMessage `json:"body"`
+}
+
+func doBatch(t *testing.T, handler *BatchHandler, body string, header http.Header) (*httptest.ResponseRecorder, []batchResultBody, bool) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/api/batch", bytes.NewBufferString(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rr := httptest.NewRecorder()
+
+	handler.HandleBatch(rr, req)
+
+	if rr.Code != http.StatusOK {
+		return rr, nil, false
+	}
+	var response struct {
+		Committed bool              `json:"committed"`
+		Results   []batchResultBody `json:"results"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	return rr, response.Results, response.Committed
+}
+
+func statuses(results []batchResultBody) []int {
+	out := make([]int, len(results))
+	for i, result := range results {
+		out[i] = result.Status
+	}
+	return out
+}
+
+func TestBatchHandler_HandleBatch_PerItemStatus(t *testing.T) {
+	chats, messages, tx := new(MockChatService), new(MockMessageService), new(fakeTransactor)
+	handler := NewBatchHandler(chats, messages, nil, tx, BatchOptions{MaxOperations: 10})
+
+	chats.On("GetChat", mock.Anything, uint(1), 20).Return(&domain.Chat{ID: 1, Title: "Чат", Version: 3}, nil)
+	messages.On("CreateMessage", mock.Anything, uint(2), "Привет").Return(nil, domain.ErrNotFound)
+	chats.On("DeleteChat", mock.Anything, uint(3), uint64(5)).Return(nil)
+
+	_, results, committed := doBatch(t, handler, `{"operations": [
+		{"id": "a", "op": "get_chat", "chat_id": 1},
+		{"id": "b", "op": "create_message", "chat_id": 2, "text": "Привет"},
+		{"id": "c", "op": "delete_chat", "chat_id": 3, "if_match": "\"5-1\""}
+	]}`, nil)
+
+	assert.True(t, committed)
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotFound, http.StatusNoContent}, statuses(results))
+	assert.Equal(t, "a", results[0].ID)
+	assert.Equal(t, `"3.0-1-json"`, results[0].ETag)
+	assert.Contains(t, string(results[1].Body), `"code":"not_found"`)
+	assert.Zero(t, tx.calls)
+	chats.AssertExpectations(t)
+	messages.AssertExpectations(t)
+}
+
+func TestBatchHandler_HandleBatch_AtomicRollsBack(t *testing.T) {
+	chats, messages, tx := new(MockChatService), new(MockMessageService), new(fakeTransactor)
+	handler := NewBatchHandler(chats, messages, nil, tx, BatchOptions{MaxOperations: 10})
+
+	chats.On("CreateChat", mock.Anything, "Новый").Return(&domain.Chat{ID: 7, Title: "Новый", Version: 1}, nil)
+	messages.On("CreateMessage", mock.Anything, uint(8), "Привет").Return(nil, domain.ErrNotFound)
+
+	_, results, committed := doBatch(t, handler, `{"atomic": true, "operations": [
+		{"op": "create_chat", "title": "Новый"},
+		{"op": "create_message", "chat_id": 8, "text": "Привет"},
+		{"op": "get_chat", "chat_id": 7}
+	]}`, nil)
+
+	assert.False(t, committed)
+	assert.Equal(t, 1, tx.calls)
+	assert.Equal(t, []int{http.StatusFailedDependency, http.StatusNotFound, http.StatusFailedDependency}, statuses(results))
+	assert.Contains(t, string(results[0].Body), problem.CodeBatchAborted)
+	chats.AssertNotCalled(t, "GetChat", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBatchHandler_HandleBatch_AdminPerItem(t *testing.T) {
+	webhooks := new(MockWebhookService)
+	handler := NewBatchHandler(nil, nil, webhooks, new(fakeTransactor), BatchOptions{MaxOperations: 10, AdminToken: "secret"})
+
+	webhooks.On("ListWebhooks", mock.Anything).Return([]domain.Webhook{}, nil)
+	body := `{"operations": [{"op": "list_webhooks"}]}`
+
+	_, results, _ := doBatch(t, handler, body, nil)
+	assert.Equal(t, []int{http.StatusUnauthorized}, statuses(results))
+
+	_, results, _ = doBatch(t, handler, body, http.Header{"Authorization": {"Bearer secret"}})
+	assert.Equal(t, []int{http.StatusOK}, statuses(results))
+	webhooks.AssertNumberOfCalls(t, "ListWebhooks", 1)
+}
+
+func TestBatchHandler_HandleBatch_RequireIfMatch(t *testing.T) {
+	chats := new(MockChatService)
+	handler := NewBatchHandler(chats, nil, nil, new(fakeTransactor), BatchOptions{MaxOperations: 10, RequireIfMatch: true})
+
+	_, results, _ := doBatch(t, handler, `{"operations": [{"op": "update_chat", "chat_id": 1, "title": "Новое"}]}`, nil)
+
+	assert.Equal(t, []int{http.StatusPreconditionRequired}, statuses(results))
+	chats.AssertNotCalled(t, "UpdateChat", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBatchHandler_HandleBatch_DeleteMessage(t *testing.T) {
+	messages, tx := new(MockMessageService), new(fakeTransactor)
+	handler := NewBatchHandler(nil, messages, nil, tx, BatchOptions{MaxOperations: 10})
+
+	messages.On("DeleteMessage", mock.Anything, uint(1), uint64(4), uint64(2)).Return(nil)
+	messages.On("DeleteMessage", mock.Anything, uint(1), uint64(5), uint64(1)).Return(domain.ErrVersionMismatch)
+	ops := `[
+		{"op": "delete_message", "chat_id": 1, "seq": 4, "if_match": "\"2-1\""},
+		{"op": "delete_message", "chat_id": 1, "seq": 5, "if_match": "\"1-1\""}
+	]`
+
+	_, results, committed := doBatch(t, handler, `{"operations": `+ops+`}`, nil)
+	assert.True(t, committed)
+	assert.Equal(t, []int{http.StatusNoContent, http.StatusPreconditionFailed}, statuses(results))
+	assert.Zero(t, tx.calls)
+
+	_, results, committed = doBatch(t, handler, `{"atomic": true, "operations": `+ops+`}`, nil)
+	assert.False(t, committed)
+	assert.Equal(t, 1, tx.calls)
+	assert.Equal(t, []int{http.StatusFailedDependency, http.StatusPreconditionFailed}, statuses(results))
+
+	_, results, committed = doBatch(t, handler, `{"atomic": true, "operations": [
+		{"op": "delete_message", "chat_id": 1, "seq": 4, "if_match": "\"2-1\""}
+	]}`, nil)
+	assert.True(t, committed)
+	assert.Equal(t, []int{http.StatusNoContent}, statuses(results))
+
+	handler = NewBatchHandler(nil, messages, nil, tx, BatchOptions{MaxOperations: 10, RequireIfMatch: true})
+	_, results, _ = doBatch(t, handler, `{"operations": [{"op": "delete_message", "chat_id": 1, "seq": 4}]}`, nil)
+	assert.Equal(t, []int{http.StatusPreconditionRequired}, statuses(results))
+	messages.AssertNumberOfCalls(t, "DeleteMessage", 5)
+}
+
+func TestBatchHandler_HandleBatch_TooManyOperations(t *testing.T) {
+	handler := NewBatchHandler(nil, nil, nil, new(fakeTransactor), BatchOptions{MaxOperations: 1})
+
+	rr, _, _ := doBatch(t, handler, `{"operations": [{"op": "get_chat", "chat_id": 1}, {"op": "get_chat", "chat_id": 2}]}`, nil)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+}
@@ -1,15 +1,16 @@
 package handlers
 
 import (
-	"chats/internal/domain"
 	"chats/internal/helpers"
+	"chats/internal/limits"
+	"chats/internal/logging"
+	"chats/internal/problem"
 	"chats/internal/services"
-	"encoding/json"
-	"errors"
-	"log/slog"
 	"net/http"
 )
 
+var errInvalidChatID = problem.New(http.StatusBadRequest, problem.CodeBadRequest, "Invalid chat ID.")
+
 type ChatHandler struct {
 	service services.ChatService
 }
@@ -21,91 +22,143 @@ func NewChatHandler(service services.ChatService) *ChatHandler {
 }
 
 func (h *ChatHandler) HandleCreateChat(w http.ResponseWriter, r *http.Request) {
-	logger := slog.Default()
+	logger := logging.FromContext(r.Context())
 
 	if r.Method != http.MethodPost {
 		logger.Warn("method not allowed", "method", r.Method)
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		problem.MethodNotAllowed(w, r)
 		return
 	}
 
-	var req domain.Chat
+	var req createChatBody
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decode(r, &req); err != nil {
 		logger.Warn("Bad Request", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Error(w, r, err)
+		return
 	}
 
 	createdChat, err := h.service.CreateChat(r.Context(), req.Title)
 	if err != nil {
-		logger.Error("Error creating chat", "error", err)
-		switch {
-		case errors.Is(err, domain.ErrInvalidInput):
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.Warn("Error creating chat", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	render(w, r, http.StatusCreated, presentChat(r, createdChat))
+}
+
+func (h *ChatHandler) HandleGetChat(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ExtractIDFromPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidChatID)
+		return
+	}
+
+	l := limits.Current()
+	limit := helpers.ParseLimitParam(r, l.DefaultHistory, l.MaxHistory)
+
+	// Revalidation only needs the chat row; messages are loaded once the
+	// client's copy turns out to be stale.
+	if isConditional(r) {
+		state, err := h.service.GetChatState(r.Context(), id)
+		if err != nil {
+			logger.Warn("Error getting chat", "error", err)
+			problem.Error(w, r, err)
 			return
-		case errors.Is(err, domain.ErrAlreadyExists):
-			http.Error(w, err.Error(), http.StatusConflict)
+		}
+		etag := chatETag(r, state, state)
+		if notModified(r, etag, state.UpdatedAt) {
+			setValidators(w, etag, state.UpdatedAt)
+			w.WriteHeader(http.StatusNotModified)
 			return
-		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(createdChat); err != nil {
-		logger.Error("Error encoding response", "error", err)
+	chat, err := h.service.GetChat(r.Context(), id, limit)
+	if err != nil {
+		logger.Warn("Error getting chat", "error", err)
+		problem.Error(w, r, err)
+		return
 	}
+	setValidators(w, chatETag(r, chat, chat), chat.UpdatedAt)
+	render(w, r, http.StatusOK, presentChat(r, chat))
 }
 
-func (h *ChatHandler) HandleGetChat(w http.ResponseWriter, r *http.Request) {
-	logger := slog.Default()
+func (h *ChatHandler) HandleUpdateChat(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
 
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodPatch {
 		logger.Warn("method not allowed", "method", r.Method)
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		problem.MethodNotAllowed(w, r)
 		return
 	}
 
 	id, err := helpers.ExtractIDFromPath(r)
 	if err != nil {
 		logger.Warn("Bad Request", "error", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
+		problem.Write(w, r, errInvalidChatID)
 		return
 	}
 
-	limit := helpers.ParseLimitParam(r, 20, 100)
-	chat, err := h.service.GetChat(r.Context(), id, limit)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		logger.Warn("Not Found", "error", err)
-		http.Error(w, "Not Found", http.StatusNotFound)
+		logger.Warn("Precondition failed", "error", err, "if_match", r.Header.Get("If-Match"))
+		problem.Error(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(chat)
+
+	var request updateChatBody
+
+	if err := decode(r, &request); err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	chat, err := h.service.UpdateChat(r.Context(), id, request.Title, version)
 	if err != nil {
-		logger.Error("Error encoding response:", "error", err)
+		logger.Warn("Error updating chat", "error", err)
+		problem.Error(w, r, err)
 		return
 	}
+
+	w.Header().Set("ETag", chatETag(r, chat, chat))
+	render(w, r, http.StatusOK, presentChat(r, chat))
 }
 
 func (h *ChatHandler) HandleDeleteChat(w http.ResponseWriter, r *http.Request) {
-	logger := slog.Default()
+	logger := logging.FromContext(r.Context())
 	if r.Method != http.MethodDelete {
 		logger.Warn("method not allowed", "method", r.Method)
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		problem.MethodNotAllowed(w, r)
 		return
 	}
 	id, err := helpers.ExtractIDFromPath(r)
 	if err != nil {
 		logger.Warn("Bad Request", "error", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
+		problem.Write(w, r, errInvalidChatID)
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		logger.Warn("Precondition failed", "error", err, "if_match", r.Header.Get("If-Match"))
+		problem.Error(w, r, err)
 		return
 	}
-	err = h.service.DeleteChat(r.Context(), id)
+	err = h.service.DeleteChat(r.Context(), id, version)
 	if err != nil {
-		logger.Warn("Not Found", "error", err)
-		http.Error(w, "Not Found", http.StatusNotFound)
+		logger.Warn("Error deleting chat", "error", err)
+		problem.Error(w, r, err)
 		return
 	}
 
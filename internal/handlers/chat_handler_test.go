@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"bytes"
+	"chats/internal/apiversion"
 	"chats/internal/domain"
+	"chats/internal/problem"
 	"context"
 	"encoding/json"
 	"errors"
@@ -36,8 +38,29 @@ func (m *MockChatService) GetChat(ctx context.Context, id uint, limit int) (*dom
 	return args.Get(0).(*domain.Chat), args.Error(1)
 }
 
-func (m *MockChatService) DeleteChat(ctx context.Context, id uint) error {
+func (m *MockChatService) GetChatState(ctx context.Context, id uint) (*domain.Chat, error) {
 	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Chat), args.Error(1)
+}
+
+func (m *MockChatService) GetChats(ctx context.Context, ids []uint) ([]domain.Chat, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]domain.Chat), args.Error(1)
+}
+
+func (m *MockChatService) UpdateChat(ctx context.Context, id uint, title string, version uint64) (*domain.Chat, error) {
+	args := m.Called(ctx, id, title, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Chat), args.Error(1)
+}
+
+func (m *MockChatService) DeleteChat(ctx context.Context, id uint, version uint64) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
@@ -90,7 +113,14 @@ func TestChatHandler_HandleCreateChat_AlreadyExists(t *testing.T) {
 	handler.HandleCreateChat(rr, req)
 
 	assert.Equal(t, http.StatusConflict, rr.Code)
-	assert.Contains(t, rr.Body.String(), domain.ErrAlreadyExists.Error())
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var response problem.Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, problem.CodeAlreadyExists, response.Code)
+	assert.Equal(t, "urn:chats:problem:already_exists", response.Type)
+	assert.Equal(t, http.StatusConflict, response.Status)
+	assert.Equal(t, domain.ErrAlreadyExists.Error(), response.Detail)
 }
 
 func TestChatHandler_HandleCreateChat_InvalidInput(t *testing.T) {
@@ -210,7 +240,7 @@ func TestChatHandler_HandleDeleteChat_Success(t *testing.T) {
 	mockService := new(MockChatService)
 	handler := NewChatHandler(mockService)
 
-	mockService.On("DeleteChat", mock.Anything, uint(1)).Return(nil)
+	mockService.On("DeleteChat", mock.Anything, uint(1), uint64(0)).Return(nil)
 
 	req := httptest.NewRequest("DELETE", "/api/chats/1", nil)
 	rr := httptest.NewRecorder()
@@ -224,7 +254,7 @@ func TestChatHandler_HandleDeleteChat_NotFound(t *testing.T) {
 	mockService := new(MockChatService)
 	handler := NewChatHandler(mockService)
 
-	mockService.On("DeleteChat", mock.Anything, uint(999)).Return(domain.ErrNotFound)
+	mockService.On("DeleteChat", mock.Anything, uint(999), uint64(0)).Return(domain.ErrNotFound)
 
 	req := httptest.NewRequest("DELETE", "/api/chats/999", nil)
 	rr := httptest.NewRecorder()
@@ -272,6 +302,7 @@ func TestChatHandler_HandleCreateChat_InternalError(t *testing.T) {
 	handler.HandleCreateChat(rr, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "database error")
 }
 
 func TestChatHandler_HandleGetChat_InternalError(t *testing.T) {
@@ -285,7 +316,9 @@ func TestChatHandler_HandleGetChat_InternalError(t *testing.T) {
 
 	handler.HandleGetChat(rr, req)
 
-	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.NotContains(t, rr.Body.String(), "database error")
 }
 
 func TestChatHandler_HandleCreateChat_ServiceError(t *testing.T) {
@@ -303,3 +336,209 @@ func TestChatHandler_HandleCreateChat_ServiceError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 }
+
+func TestChatHandler_HandleGetChat_V2Shape(t *testing.T) {
+	mockService := new(MockChatService)
+	handler := NewChatHandler(mockService)
+
+	chat := &domain.Chat{ID: 1, Title: "Тестовый чат", Message: []domain.Message{{ID: 5, ChatID: 1, Seq: 1, Version: 1, Text: "привет"}}}
+	mockService.On("GetChat", mock.Anything, uint(1), 20).Return(chat, nil)
+
+	req := httptest.NewRequest("GET", "/api/v2/chats/1", nil)
+	req = req.WithContext(apiversion.WithVersion(req.Context(), apiversion.V2))
+	rr := httptest.NewRecorder()
+
+	handler.HandleGetChat(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.NotContains(t, response, "message")
+	assert.JSONEq(t, `[{"id":5,"chat_id":1,"seq":1,"version":1,"text":"привет","created_at":"0001-01-01T00:00:00Z"}]`, string(response["messages"]))
+}
+
+func TestChatHandler_HandleGetChat_MessageOrder(t *testing.T) {
+	// The service returns the latest messages newest first.
+	chat := &domain.Chat{ID: 1, Title: "Тестовый чат", Message: []domain.Message{
+		{ID: 7, ChatID: 1, Seq: 3, Text: "третье"},
+		{ID: 6, ChatID: 1, Seq: 2, Text: "второе"},
+	}}
+	mockService := new(MockChatService)
+	handler := NewChatHandler(mockService)
+	mockService.On("GetChat", mock.Anything, uint(1), 20).Return(chat, nil)
+
+	seqs := func(messages []domain.Message) []uint64 {
+		var out []uint64
+		for _, m := range messages {
+			out = append(out, m.Seq)
+		}
+		return out
+	}
+
+	rr := httptest.NewRecorder()
+	handler.HandleGetChat(rr, httptest.NewRequest("GET", "/api/chats/1", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var v1 struct {
+		Message []domain.Message `json:"message"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &v1))
+	assert.Equal(t, []uint64{3, 2}, seqs(v1.Message), "v1 keeps newest first")
+
+	req := httptest.NewRequest("GET", "/api/v2/chats/1", nil)
+	req = req.WithContext(apiversion.WithVersion(req.Context(), apiversion.V2))
+	rr = httptest.NewRecorder()
+	handler.HandleGetChat(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var v2 struct {
+		Messages []domain.Message `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &v2))
+	assert.Equal(t, []uint64{2, 3}, seqs(v2.Messages), "v2 lists them in history order")
+	assert.Equal(t, uint64(3), chat.Message[0].Seq, "the service's slice is left as is")
+}
+
+func TestChatHandler_HandleGetChat_ConditionalRequests(t *testing.T) {
+	updated := time.Date(2026, 10, 19, 12, 0, 30, 500, time.UTC)
+	chat := &domain.Chat{ID: 1, Title: "Тестовый чат", LastSeq: 42, Version: 57, HistoryVersion: 9, UpdatedAt: updated}
+
+	mockService := new(MockChatService)
+	handler := NewChatHandler(mockService)
+	mockService.On("GetChat", mock.Anything, uint(1), 20).Return(chat, nil)
+	mockService.On("GetChatState", mock.Anything, uint(1)).Return(chat, nil)
+
+	rr := httptest.NewRecorder()
+	handler.HandleGetChat(rr, httptest.NewRequest("GET", "/api/chats/1", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "Mon, 19 Oct 2026 12:00:30 GMT", rr.Header().Get("Last-Modified"))
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+
+	msgpack := httptest.NewRequest("GET", "/api/chats/1", nil)
+	msgpack.Header.Set("Accept", "application/msgpack")
+	msgpack.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.HandleGetChat(rr, msgpack)
+	assert.Equal(t, http.StatusOK, rr.Code, "another format is another representation")
+	assert.Equal(t, `"57.9-1-msgpack"`, rr.Header().Get("ETag"))
+
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		want    int
+	}{
+		{"matching etag", "/api/chats/1", map[string]string{"If-None-Match": `"other", ` + etag}, http.StatusNotModified},
+		{"weak etag", "/api/chats/1", map[string]string{"If-None-Match": "W/" + etag}, http.StatusNotModified},
+		{"stale etag", "/api/chats/1", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"any etag", "/api/chats/1", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"not modified since", "/api/chats/1", map[string]string{"If-Modified-Since": "Mon, 19 Oct 2026 12:00:30 GMT"}, http.StatusNotModified},
+		{"modified since", "/api/chats/1", map[string]string{"If-Modified-Since": "Mon, 19 Oct 2026 12:00:29 GMT"}, http.StatusOK},
+		{"etag wins over date", "/api/chats/1", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Mon, 19 Oct 2026 12:00:30 GMT"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.HandleGetChat(rr, req)
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.want == http.StatusNotModified {
+				assert.Equal(t, etag, rr.Header().Get("ETag"))
+				assert.Empty(t, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestChatHandler_HandleGetChat_NotModifiedSkipsMessages(t *testing.T) {
+	chat := &domain.Chat{ID: 1, LastSeq: 3, Version: 4, UpdatedAt: time.Now()}
+
+	mockService := new(MockChatService)
+	handler := NewChatHandler(mockService)
+	mockService.On("GetChatState", mock.Anything, uint(1)).Return(chat, nil)
+
+	v1 := httptest.NewRequest("GET", "/api/chats/1", nil)
+	v2 := v1.WithContext(apiversion.WithVersion(v1.Context(), apiversion.V2))
+	assert.NotEqual(t, chatETag(v1, chat, chat), chatETag(v2, chat, chat))
+
+	v2.Header.Set("If-None-Match", chatETag(v2, chat, chat))
+	rr := httptest.NewRecorder()
+
+	handler.HandleGetChat(rr, v2)
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Contains(t, rr.Header().Values("Vary"), "Accept")
+	mockService.AssertNotCalled(t, "GetChat", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChatHandler_HandleUpdateChat(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifMatch     string
+		wantVersion uint64
+		serviceErr  error
+		wantStatus  int
+	}{
+		{"etag", `"4-1"`, 4, nil, http.StatusOK},
+		{"bare version", `"4"`, 4, nil, http.StatusOK},
+		{"history moved on", `"4.12-1-json"`, 4, nil, http.StatusOK},
+		{"no header", "", 0, nil, http.StatusOK},
+		{"any", "*", 0, nil, http.StatusOK},
+		{"stale", `"3-1"`, 3, domain.ErrVersionMismatch, http.StatusPreconditionFailed},
+		{"weak etag", `W/"4-1"`, 0, nil, http.StatusPreconditionFailed},
+		{"several etags", `"3-1", "4-1"`, 0, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockChatService)
+			handler := NewChatHandler(mockService)
+
+			updated := &domain.Chat{ID: 1, Title: "Новое название", Version: tt.wantVersion + 1}
+			if tt.serviceErr != nil {
+				mockService.On("UpdateChat", mock.Anything, uint(1), "Новое название", tt.wantVersion).Return(nil, tt.serviceErr)
+			} else {
+				mockService.On("UpdateChat", mock.Anything, uint(1), "Новое название", tt.wantVersion).Return(updated, nil)
+			}
+
+			req := httptest.NewRequest("PATCH", "/api/chats/1", bytes.NewBufferString(`{"title": "Новое название"}`))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.HandleUpdateChat(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, chatETag(req, updated, updated), rr.Header().Get("ETag"))
+			} else {
+				assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestChatHandler_HandleDeleteChat_IfMatch(t *testing.T) {
+	mockService := new(MockChatService)
+	handler := NewChatHandler(mockService)
+
+	mockService.On("DeleteChat", mock.Anything, uint(1), uint64(7)).Return(domain.ErrVersionMismatch)
+
+	req := httptest.NewRequest("DELETE", "/api/chats/1", nil)
+	req.Header.Set("If-Match", `"7-1"`)
+	rr := httptest.NewRecorder()
+
+	handler.HandleDeleteChat(rr, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"chats/internal/apiversion"
+	"chats/internal/domain"
+	"chats/internal/problem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errMultipleETags = problem.New(http.StatusBadRequest, problem.CodeBadRequest, "If-Match must hold a single entity tag.")
+
+// chatCacheControl lets clients and caches keep a chat but makes them
+// revalidate it on every use, which is cheap thanks to the validators below.
+const chatCacheControl = "no-cache"
+
+// versionETag is the strong validator of a message at version, sent
+// as v. The API version and the negotiated format are part of it because
+// one URL serves all of these representations.
+func versionETag(r *http.Request, version uint64, v any) string {
+	c, _ := negotiate(r, v)
+	return fmt.Sprintf(`"%d-%d-%s"`, version, apiversion.FromContext(r.Context()), c.Name())
+}
+
+// chatETag is the versionETag of a chat. The history version is part of it
+// because the chat is served with its latest messages; If-Match reads only
+// the leading chat version, so posting a message does not fail a rename.
+func chatETag(r *http.Request, chat *domain.Chat, v any) string {
+	c, _ := negotiate(r, v)
+	return fmt.Sprintf(`"%d.%d-%d-%s"`, chat.Version, chat.HistoryVersion, apiversion.FromContext(r.Context()), c.Name())
+}
+
+// ifMatchVersion returns the version If-Match requires; zero when the header
+// is absent or "*", which leave the change unconditional. A bare `"<version>"`
+// is accepted too, for clients that build it from the version field.
+func ifMatchVersion(r *http.Request) (uint64, error) {
+	return parseIfMatch(r.Header.Get("If-Match"))
+}
+
+func parseIfMatch(header string) (uint64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errMultipleETags
+	}
+
+	// If-Match uses the strong comparison, so a weak tag never matches.
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok || !strings.HasSuffix(tag, `"`) {
+		return 0, domain.ErrVersionMismatch
+	}
+	tag = strings.TrimSuffix(tag, `"`)
+	tag, _, _ = strings.Cut(tag, "-")
+	tag, _, _ = strings.Cut(tag, ".")
+
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil || version == 0 {
+		return 0, domain.ErrVersionMismatch
+	}
+	return version, nil
+}
+
+func setValidators(w http.ResponseWriter, etag string, modified time.Time) {
+	varyAccept(w)
+	w.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", chatCacheControl)
+}
+
+func isConditional(r *http.Request) bool {
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is no
+// If-None-Match, as RFC 9110 orders them.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for candidate := range strings.SplitSeq(header, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// If-None-Match uses the weak comparison.
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
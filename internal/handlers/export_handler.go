@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bufio"
+	"chats/internal/domain"
+	"chats/internal/export"
+	"chats/internal/helpers"
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"chats/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ExportHandler struct {
+	service services.ExportService
+	now     func() time.Time
+}
+
+func NewExportHandler(service services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		service: service,
+		now:     time.Now,
+	}
+}
+
+func (h *ExportHandler) HandleExportChat(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ExtractIDFromPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidChatID)
+		return
+	}
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "json"
+	}
+	format, ok := export.Lookup(name)
+	if !ok {
+		logger.Warn("Bad Request", "error", "unknown export format", "format", name)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest,
+			"format must be one of "+strings.Join(export.Names(), ", ")+"."))
+		return
+	}
+
+	from, err := parseExportBound(r.URL.Query().Get("from"), false)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, "from "+err.Error()))
+		return
+	}
+	to, err := parseExportBound(r.URL.Query().Get("to"), true)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, "to "+err.Error()))
+		return
+	}
+
+	chat, messages, err := h.service.ExportChat(r.Context(), id, domain.HistoryFilter{From: from, To: to})
+	if err != nil {
+		logger.Warn("Error exporting chat", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	// Long transcripts outlast the server's write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chat-%d.%s"`, chat.ID, format.Extension))
+
+	buf := bufio.NewWriter(w)
+	enc := format.New(buf)
+	write := func() error {
+		if err := enc.Begin(export.Header{Chat: chat, From: from, To: to, ExportedAt: h.now()}); err != nil {
+			return err
+		}
+		for message, err := range messages {
+			if err != nil {
+				return err
+			}
+			if err := enc.Message(message); err != nil {
+				return err
+			}
+		}
+		if err := enc.End(); err != nil {
+			return err
+		}
+		return buf.Flush()
+	}
+
+	if err := write(); err != nil {
+		logger.Error("Error writing export", "error", err, "chat_id", id, "format", format.Name)
+		// The status line may already be out; aborting the connection makes
+		// the transcript visibly truncated instead of complete-looking.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// parseExportBound accepts an RFC 3339 timestamp or a date. A date as the
+// upper bound includes that whole day.
+func parseExportBound(raw string, upper bool) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, errors.New("must be a date (2006-01-02) or an RFC 3339 timestamp")
+	}
+	if upper {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) ExportChat(ctx context.Context, chatID uint, filter domain.HistoryFilter) (*domain.Chat, iter.Seq2[domain.Message, error], error) {
+	args := m.Called(ctx, chatID, filter)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*domain.Chat), args.Get(1).(iter.Seq2[domain.Message, error]), args.Error(2)
+}
+
+func messagesOf(messages ...domain.Message) iter.Seq2[domain.Message, error] {
+	return func(yield func(domain.Message, error) bool) {
+		for _, message := range messages {
+			if !yield(message, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestExportHandler_HandleExportChat_CSV(t *testing.T) {
+	mockService := new(MockExportService)
+	handler := NewExportHandler(mockService)
+
+	filter := domain.HistoryFilter{
+		From: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	messages := messagesOf(domain.Message{ID: 3, Seq: 1, Author: "ann", Text: "hi, all", CreatedAt: filter.From})
+	mockService.On("ExportChat", mock.Anything, uint(7), filter).Return(&domain.Chat{ID: 7, Title: "general"}, messages, nil)
+
+	req := httptest.NewRequest("GET", "/api/chats/7/export?format=csv&from=2026-03-01&to=2026-03-31", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleExportChat(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="chat-7.csv"`, rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "seq,id,created_at,edited_at,author,text\n1,3,2026-03-01T00:00:00Z,,ann,\"hi, all\"\n", rr.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestExportHandler_HandleExportChat_BadRequest(t *testing.T) {
+	for _, query := range []string{"format=pdf", "from=yesterday", "to=2026-13-01"} {
+		mockService := new(MockExportService)
+		handler := NewExportHandler(mockService)
+
+		req := httptest.NewRequest("GET", "/api/chats/7/export?"+query, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleExportChat(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		mockService.AssertNotCalled(t, "ExportChat")
+	}
+}
+
+func TestExportHandler_HandleExportChat_NotFound(t *testing.T) {
+	mockService := new(MockExportService)
+	handler := NewExportHandler(mockService)
+
+	mockService.On("ExportChat", mock.Anything, uint(7), domain.HistoryFilter{}).Return(nil, nil, domain.ErrNotFound)
+
+	req := httptest.NewRequest("GET", "/api/chats/7/export", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleExportChat(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestExportHandler_HandleExportChat_AbortsOnReadError(t *testing.T) {
+	mockService := new(MockExportService)
+	handler := NewExportHandler(mockService)
+
+	failing := func(yield func(domain.Message, error) bool) {
+		if yield(domain.Message{Seq: 1, Text: "one"}, nil) {
+			yield(domain.Message{}, errors.New("pq: connection reset by peer"))
+		}
+	}
+	mockService.On("ExportChat", mock.Anything, uint(7), domain.HistoryFilter{}).
+		Return(&domain.Chat{ID: 7}, iter.Seq2[domain.Message, error](failing), nil)
+
+	req := httptest.NewRequest("GET", "/api/chats/7/export?format=ndjson", nil)
+	rr := httptest.NewRecorder()
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.HandleExportChat(rr, req)
+	})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"chats/internal/health"
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"net/http"
+)
+
+type HealthHandler struct {
+	readiness *health.Checker
+}
+
+func NewHealthHandler(readiness *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		readiness: readiness,
+	}
+}
+
+// HandleLive reports that the process is up and serving. It checks no
+// dependencies: restarting the server would not bring Postgres back.
+func (h *HealthHandler) HandleLive(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render(w, r, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// HandleReady reports whether the replica should receive traffic, with
+// the outcome of every dependency check. It answers 503 while a check
+// fails or the server is shutting down.
+func (h *HealthHandler) HandleReady(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	report := h.readiness.Report(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		logger.Warn("Not ready", "status", report.Status, "checks", report.Checks)
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render(w, r, status, report)
+}
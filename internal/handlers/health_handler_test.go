@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"chats/internal/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_HandleLive(t *testing.T) {
+	handler := NewHealthHandler(health.NewChecker(time.Second, 0))
+
+	req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+	w := httptest.NewRecorder()
+	handler.HandleLive(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestHealthHandler_HandleReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		dbErr      error
+		draining   bool
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "ready", wantCode: http.StatusOK, wantStatus: health.StatusOK},
+		{name: "database down", dbErr: errors.New("connection refused"), wantCode: http.StatusServiceUnavailable, wantStatus: health.StatusUnavailable},
+		{name: "draining", draining: true, wantCode: http.StatusServiceUnavailable, wantStatus: health.StatusDraining},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.NewChecker(time.Second, 0)
+			checker.Register("database", func(context.Context) error { return tt.dbErr })
+			if tt.draining {
+				checker.SetDraining()
+			}
+			handler := NewHealthHandler(checker)
+
+			req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+			w := httptest.NewRecorder()
+			handler.HandleReady(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			var report health.Report
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tt.wantStatus, report.Status)
+			if tt.dbErr != nil {
+				assert.Equal(t, tt.dbErr.Error(), report.Checks["database"].Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"chats/internal/helpers"
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"chats/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ImportNextOffsetHeader carries the resume offset on every import response,
+// including failures, where the body is a problem instead of the report.
+const ImportNextOffsetHeader = "Import-Next-Offset"
+
+type ImportHandler struct {
+	service services.ImportService
+}
+
+func NewImportHandler(service services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		service: service,
+	}
+}
+
+func (h *ImportHandler) HandleImportMessages(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ExtractIDFromPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidChatID)
+		return
+	}
+
+	offset := 0
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			logger.Warn("Bad Request", "error", "invalid offset", "offset", raw)
+			problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, "offset must be a non-negative integer."))
+			return
+		}
+	}
+
+	// Large uploads outlast the server's read and write timeouts.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	report, err := h.service.ImportMessages(r.Context(), id, r.Body, offset)
+	if report != nil {
+		w.Header().Set(ImportNextOffsetHeader, strconv.Itoa(report.NextOffset))
+	}
+	if err != nil {
+		logger.Warn("Error importing messages", "error", err, "chat_id", id)
+		problem.Error(w, r, err)
+		return
+	}
+
+	logger.Info("Messages imported", "chat_id", id, "imported", report.Imported, "failed", report.Failed, "skipped", report.Skipped)
+	render(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"chats/internal/domain"
+	"chats/internal/problem"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockImportService struct {
+	mock.Mock
+}
+
+func (m *MockImportService) ImportMessages(ctx context.Context, chatID uint, body io.Reader, offset int) (*domain.ImportReport, error) {
+	args := m.Called(ctx, chatID, body, offset)
+	report, _ := args.Get(0).(*domain.ImportReport)
+	return report, args.Error(1)
+}
+
+func TestImportHandler_HandleImportMessages_Success(t *testing.T) {
+	mockService := new(MockImportService)
+	handler := NewImportHandler(mockService)
+
+	report := &domain.ImportReport{Imported: 2, Failed: 1, NextOffset: 3, Errors: []domain.ImportLineError{{Line: 2, Error: "text is required"}}}
+	mockService.On("ImportMessages", mock.Anything, uint(5), mock.Anything, 10).Return(report, nil)
+
+	req := httptest.NewRequest("POST", "/api/chats/5/messages:import?offset=10", strings.NewReader("{}\n"))
+	rr := httptest.NewRecorder()
+
+	handler.HandleImportMessages(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "3", rr.Header().Get(ImportNextOffsetHeader))
+
+	var response domain.ImportReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, *report, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestImportHandler_HandleImportMessages_FailureKeepsOffset(t *testing.T) {
+	mockService := new(MockImportService)
+	handler := NewImportHandler(mockService)
+
+	mockService.On("ImportMessages", mock.Anything, uint(5), mock.Anything, 0).
+		Return(&domain.ImportReport{Imported: 1000, NextOffset: 1004}, errors.New("pq: connection reset by peer"))
+
+	req := httptest.NewRequest("POST", "/api/chats/5/messages:import", strings.NewReader("{}\n"))
+	rr := httptest.NewRecorder()
+
+	handler.HandleImportMessages(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+	assert.Equal(t, "1004", rr.Header().Get(ImportNextOffsetHeader))
+}
+
+func TestImportHandler_HandleImportMessages_InvalidOffset(t *testing.T) {
+	mockService := new(MockImportService)
+	handler := NewImportHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/api/chats/5/messages:import?offset=-1", strings.NewReader("{}\n"))
+	rr := httptest.NewRecorder()
+
+	handler.HandleImportMessages(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "ImportMessages")
+}
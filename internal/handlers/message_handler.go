@@ -3,14 +3,20 @@ package handlers
 import (
 	"chats/internal/domain"
 	"chats/internal/helpers"
+	"chats/internal/limits"
+	"chats/internal/logging"
+	"chats/internal/problem"
 	"chats/internal/services"
-	"encoding/json"
-	"errors"
-	"log/slog"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/go-chi/chi/v5"
 )
 
+var errInvalidSeq = problem.New(http.StatusBadRequest, problem.CodeBadRequest, "Invalid message seq.")
+
 type MessageHandler struct {
 	service services.MessageService
 }
@@ -22,62 +28,183 @@ func NewMessageHandler(service services.MessageService) *MessageHandler {
 }
 
 func (h *MessageHandler) HandleCreateMessage(w http.ResponseWriter, r *http.Request) {
-	logger := slog.Default()
+	logger := logging.FromContext(r.Context())
 
 	if r.Method != http.MethodPost {
 		logger.Warn("method not allowed", "method", r.Method)
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		problem.MethodNotAllowed(w, r)
 		return
 	}
 
 	id, err := helpers.ExtractIDFromPath(r)
 	if err != nil {
 		logger.Warn("Bad Request", "error", err)
-		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		problem.Write(w, r, errInvalidChatID)
 		return
 	}
 
-	var request struct {
-		Text string `json:"text"`
-	}
+	var request createMessageBody
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	if err := decode(r, &request); err != nil {
 		logger.Warn("Bad Request", "error", err)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
 	request.Text = strings.TrimSpace(request.Text)
 	if request.Text == "" {
 		logger.Warn("Bad Request", "error", "Text cannot be empty")
-		http.Error(w, "Text cannot be empty", http.StatusBadRequest)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidInput, "Text cannot be empty."))
 		return
 	}
 
-	if len(request.Text) > 5000 {
+	if maxLength := limits.Current().MaxMessageLength; len(request.Text) > maxLength {
 		logger.Warn("Bad Request", "error", "Text too long")
-		http.Error(w, "Text must be 5000 characters or less", http.StatusBadRequest)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidInput, fmt.Sprintf("Text must be %d characters or less.", maxLength)))
 		return
 	}
 
 	message, err := h.service.CreateMessage(r.Context(), id, request.Text)
 	if err != nil {
-		logger.Error("Error creating message", "error", err)
+		logger.Warn("Error creating message", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	render(w, r, http.StatusCreated, message)
+}
+
+func (h *MessageHandler) HandleUpdateMessage(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodPatch {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ExtractIDFromPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidChatID)
+		return
+	}
+
+	seq, err := strconv.ParseUint(chi.URLParam(r, "seq"), 10, 64)
+	if err != nil || seq == 0 {
+		logger.Warn("Bad Request", "error", "invalid seq", "seq", chi.URLParam(r, "seq"))
+		problem.Write(w, r, errInvalidSeq)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		logger.Warn("Precondition failed", "error", err, "if_match", r.Header.Get("If-Match"))
+		problem.Error(w, r, err)
+		return
+	}
+
+	var request updateMessageBody
+
+	if err := decode(r, &request); err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	message, err := h.service.UpdateMessage(r.Context(), id, seq, request.Text, version)
+	if err != nil {
+		logger.Warn("Error updating message", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(r, message.Version, message))
+	render(w, r, http.StatusOK, message)
+}
+
+func (h *MessageHandler) HandleDeleteMessage(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodDelete {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ExtractIDFromPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidChatID)
+		return
+	}
 
-		switch {
-		case errors.Is(err, domain.ErrNotFound):
-			http.Error(w, "Chat not found", http.StatusNotFound)
-		case errors.Is(err, domain.ErrInvalidInput):
-			http.Error(w, "Invalid input", http.StatusBadRequest)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+	seq, err := strconv.ParseUint(chi.URLParam(r, "seq"), 10, 64)
+	if err != nil || seq == 0 {
+		logger.Warn("Bad Request", "error", "invalid seq", "seq", chi.URLParam(r, "seq"))
+		problem.Write(w, r, errInvalidSeq)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(message); err != nil {
-		logger.Error("Error encoding response", "error", err)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		logger.Warn("Precondition failed", "error", err, "if_match", r.Header.Get("If-Match"))
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err := h.service.DeleteMessage(r.Context(), id, seq, version); err != nil {
+		logger.Warn("Error deleting message", "error", err)
+		problem.Error(w, r, err)
+		return
 	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MessageHandler) HandleListMessages(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ExtractIDFromPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidChatID)
+		return
+	}
+
+	afterSeq, err := helpers.ParseSeqParam(r, "after_seq")
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, err.Error()))
+		return
+	}
+
+	beforeSeq, err := helpers.ParseSeqParam(r, "before_seq")
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, err.Error()))
+		return
+	}
+
+	l := limits.Current()
+	cursor := domain.MessageCursor{
+		AfterSeq:  afterSeq,
+		BeforeSeq: beforeSeq,
+		Limit:     helpers.ParseLimitParam(r, l.DefaultHistory, l.MaxHistory),
+	}
+
+	messages, err := h.service.ListMessages(r.Context(), id, cursor)
+	if err != nil {
+		logger.Warn("Error listing messages", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	render(w, r, http.StatusOK, presentMessages(r, messages))
 }
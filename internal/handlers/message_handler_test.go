@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"bytes"
+	"chats/internal/apiversion"
 	"chats/internal/domain"
+	"chats/internal/limits"
+	"chats/internal/middleware"
 	"context"
 	"encoding/json"
 	"net/http"
@@ -27,6 +30,32 @@ func (m *MockMessageService) CreateMessage(ctx context.Context, chatID uint, tex
 	return args.Get(0).(*domain.Message), args.Error(1)
 }
 
+func (m *MockMessageService) UpdateMessage(ctx context.Context, chatID uint, seq uint64, text string, version uint64) (*domain.Message, error) {
+	args := m.Called(ctx, chatID, seq, text, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Message), args.Error(1)
+}
+
+func (m *MockMessageService) DeleteMessage(ctx context.Context, chatID uint, seq uint64, version uint64) error {
+	args := m.Called(ctx, chatID, seq, version)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) ListMessages(ctx context.Context, chatID uint, cursor domain.MessageCursor) ([]domain.Message, error) {
+	args := m.Called(ctx, chatID, cursor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Message), args.Error(1)
+}
+
+func (m *MockMessageService) ListLatestMessages(ctx context.Context, chatIDs []uint, limit int) (map[uint][]domain.Message, error) {
+	args := m.Called(ctx, chatIDs, limit)
+	return args.Get(0).(map[uint][]domain.Message), args.Error(1)
+}
+
 func TestMessageHandler_HandleCreateMessage_Success(t *testing.T) {
 	mockService := new(MockMessageService)
 	handler := NewMessageHandler(mockService)
@@ -128,6 +157,25 @@ func TestMessageHandler_HandleCreateMessage_TextTooLong(t *testing.T) {
 	mockService.AssertNotCalled(t, "CreateMessage")
 }
 
+func TestMessageHandler_HandleCreateMessage_ConfiguredLimit(t *testing.T) {
+	limits.Set(limits.Limits{MaxMessageLength: 10, MaxTitleLength: 200, DefaultHistory: 20, MaxHistory: 100})
+	defer limits.Set(limits.Default)
+
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	requestBody, _ := json.Marshal(map[string]string{"text": "eleven char"})
+	req := httptest.NewRequest("POST", "/api/chats/123/messages", bytes.NewBuffer(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCreateMessage(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Text must be 10 characters or less.")
+	mockService.AssertNotCalled(t, "CreateMessage")
+}
+
 func TestMessageHandler_HandleCreateMessage_TextWithSpaces(t *testing.T) {
 	mockService := new(MockMessageService)
 	handler := NewMessageHandler(mockService)
@@ -272,3 +320,201 @@ func TestMessageHandler_HandleCreateMessage_EncodingError(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, rr.Code)
 }
+
+func TestMessageHandler_HandleListMessages_ResumesAfterSeq(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	expected := []domain.Message{
+		{ID: 11, ChatID: 123, Seq: 6, Text: "шесть"},
+		{ID: 12, ChatID: 123, Seq: 7, Text: "семь"},
+	}
+	cursor := domain.MessageCursor{AfterSeq: 5, Limit: 2}
+	mockService.On("ListMessages", mock.Anything, uint(123), cursor).Return(expected, nil)
+
+	req := httptest.NewRequest("GET", "/api/chats/123/messages?after_seq=5&limit=2", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleListMessages(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response []domain.Message
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response, 2)
+	assert.Equal(t, uint64(6), response[0].Seq)
+	assert.Equal(t, uint64(7), response[1].Seq)
+}
+
+func TestMessageHandler_HandleListMessages_EmptyPage(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	mockService.On("ListMessages", mock.Anything, uint(123), domain.MessageCursor{Limit: 20}).Return(nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/chats/123/messages", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleListMessages(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+}
+
+func TestMessageHandler_HandleListMessages_InvalidCursor(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/api/chats/123/messages?after_seq=-1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleListMessages(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "ListMessages")
+}
+
+func TestMessageHandler_HandleListMessages_ChatNotFound(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	mockService.On("ListMessages", mock.Anything, uint(999), domain.MessageCursor{Limit: 20}).Return(nil, domain.ErrNotFound)
+
+	req := httptest.NewRequest("GET", "/api/chats/999/messages", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleListMessages(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestMessageHandler_HandleListMessages_V2Envelope(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	expected := []domain.Message{
+		{ID: 11, ChatID: 123, Seq: 6, Text: "шесть"},
+		{ID: 12, ChatID: 123, Seq: 7, Text: "семь"},
+	}
+	mockService.On("ListMessages", mock.Anything, uint(123), domain.MessageCursor{Limit: 20}).Return(expected, nil)
+
+	req := httptest.NewRequest("GET", "/api/v2/chats/123/messages", nil)
+	req = req.WithContext(apiversion.WithVersion(req.Context(), apiversion.V2))
+	rr := httptest.NewRecorder()
+
+	handler.HandleListMessages(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Data   []domain.Message `json:"data"`
+		Cursor struct {
+			BeforeSeq uint64 `json:"before_seq"`
+			AfterSeq  uint64 `json:"after_seq"`
+		} `json:"cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Data, 2)
+	assert.Equal(t, uint64(6), response.Cursor.BeforeSeq)
+	assert.Equal(t, uint64(7), response.Cursor.AfterSeq)
+}
+
+func TestMessageHandler_HandleUpdateMessage(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	edited := time.Now()
+	updated := &domain.Message{ID: 9, ChatID: 123, Seq: 4, Version: 3, Text: "исправлено", EditedAt: &edited}
+	mockService.On("UpdateMessage", mock.Anything, uint(123), uint64(4), "исправлено", uint64(2)).Return(updated, nil)
+
+	req := httptest.NewRequest("PATCH", "/api/chats/123/messages/4", bytes.NewBufferString(`{"text": "исправлено"}`))
+	req = withURLParams(req, map[string]string{"id": "123", "seq": "4"})
+	req.Header.Set("If-Match", `"2-1"`)
+	rr := httptest.NewRecorder()
+
+	handler.HandleUpdateMessage(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"3-1-json"`, rr.Header().Get("ETag"))
+
+	var response domain.Message
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, uint64(3), response.Version)
+	assert.NotNil(t, response.EditedAt)
+}
+
+func TestMessageHandler_HandleUpdateMessage_Errors(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	mockService.On("UpdateMessage", mock.Anything, uint(123), uint64(4), "текст", uint64(2)).Return(nil, domain.ErrVersionMismatch)
+	mockService.On("UpdateMessage", mock.Anything, uint(123), uint64(5), "текст", uint64(0)).Return(nil, domain.ErrNotFound)
+
+	tests := []struct {
+		seq     string
+		ifMatch string
+		want    int
+	}{
+		{"4", `"2-1"`, http.StatusPreconditionFailed},
+		{"5", "", http.StatusNotFound},
+		{"0", "", http.StatusBadRequest},
+		{"abc", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("PATCH", "/api/chats/123/messages/"+tt.seq, bytes.NewBufferString(`{"text": "текст"}`))
+		req = withURLParams(req, map[string]string{"id": "123", "seq": tt.seq})
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+		rr := httptest.NewRecorder()
+
+		handler.HandleUpdateMessage(rr, req)
+
+		assert.Equal(t, tt.want, rr.Code, tt.seq)
+	}
+}
+
+func TestMessageHandler_HandleDeleteMessage(t *testing.T) {
+	mockService := new(MockMessageService)
+	handler := NewMessageHandler(mockService)
+
+	mockService.On("DeleteMessage", mock.Anything, uint(123), uint64(4), uint64(2)).Return(nil)
+	mockService.On("DeleteMessage", mock.Anything, uint(123), uint64(4), uint64(1)).Return(domain.ErrVersionMismatch)
+	mockService.On("DeleteMessage", mock.Anything, uint(123), uint64(5), uint64(0)).Return(domain.ErrNotFound)
+
+	tests := []struct {
+		name      string
+		seq       string
+		ifMatch   string
+		requireIf bool
+		want      int
+	}{
+		{"deleted", "4", `"2-1"`, true, http.StatusNoContent},
+		{"stale version", "4", `"1-1"`, false, http.StatusPreconditionFailed},
+		{"If-Match required", "4", "", true, http.StatusPreconditionRequired},
+		{"unknown message", "5", "", false, http.StatusNotFound},
+		{"bad seq", "abc", "", false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h http.Handler = http.HandlerFunc(handler.HandleDeleteMessage)
+			if tt.requireIf {
+				h = middleware.RequireIfMatch(h)
+			}
+
+			req := httptest.NewRequest("DELETE", "/api/chats/123/messages/"+tt.seq, nil)
+			req = withURLParams(req, map[string]string{"id": "123", "seq": tt.seq})
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.want, rr.Code, rr.Body.String())
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "DeleteMessage", 3)
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	chatsv1 "chats/api/chats/v1"
+	"chats/internal/apiversion"
+	"chats/internal/codec"
+	"chats/internal/domain"
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"chats/internal/protoconv"
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// v1 encodes the domain types as they are; v2 shapes are defined here so
+// both versions are served by the same handlers and services.
+
+type chatV2 struct {
+	ID        uint             `json:"id"`
+	Title     string           `json:"title"`
+	LastSeq   uint64           `json:"last_seq"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	Messages  []domain.Message `json:"messages"`
+
+	chat *domain.Chat
+}
+
+type listV2[T any] struct {
+	Data []T `json:"data"`
+}
+
+type messagePageV2 struct {
+	Data []domain.Message `json:"data"`
+	// Cursor holds the seq bounds of the page: pass before_seq to go back in
+	// history, after_seq to fetch newer messages. Omitted for empty pages.
+	Cursor *pageCursorV2 `json:"cursor,omitempty"`
+}
+
+type pageCursorV2 struct {
+	BeforeSeq uint64 `json:"before_seq"`
+	AfterSeq  uint64 `json:"after_seq"`
+}
+
+func presentChat(r *http.Request, chat *domain.Chat) any {
+	if apiversion.FromContext(r.Context()) < apiversion.V2 {
+		return chat
+	}
+
+	// The repository returns the latest messages newest first, as v1 has
+	// always listed them; v2 lists them in history order.
+	messages := slices.Clone(chat.Message)
+	if messages == nil {
+		messages = []domain.Message{}
+	}
+	slices.SortFunc(messages, func(a, b domain.Message) int { return cmp.Compare(a.Seq, b.Seq) })
+	return chatV2{
+		ID:        chat.ID,
+		Title:     chat.Title,
+		LastSeq:   chat.LastSeq,
+		CreatedAt: chat.CreatedAt,
+		UpdatedAt: chat.UpdatedAt,
+		Messages:  messages,
+		chat:      chat,
+	}
+}
+
+func presentMessages(r *http.Request, messages []domain.Message) any {
+	if messages == nil {
+		messages = []domain.Message{}
+	}
+	if apiversion.FromContext(r.Context()) < apiversion.V2 {
+		return messages
+	}
+
+	page := messagePageV2{Data: messages}
+	if len(messages) > 0 {
+		page.Cursor = &pageCursorV2{
+			BeforeSeq: messages[0].Seq,
+			AfterSeq:  messages[len(messages)-1].Seq,
+		}
+	}
+	return page
+}
+
+func presentList[T any](r *http.Request, items []T) any {
+	if items == nil {
+		items = []T{}
+	}
+	if apiversion.FromContext(r.Context()) < apiversion.V2 {
+		return items
+	}
+	return listV2[T]{Data: items}
+}
+
+var (
+	errInvalidBody          = problem.New(http.StatusBadRequest, problem.CodeBadRequest, "The request body could not be decoded.")
+	errUnsupportedMediaType = problem.New(http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+		"Send the body as JSON or MessagePack; Protobuf is accepted where a chats.v1 message exists for it.")
+)
+
+// render writes v in the format the client prefers by Accept. Values
+// without a protobuf message skip Protobuf for the next acceptable format,
+// and JSON is used when nothing else is acceptable.
+func render(w http.ResponseWriter, r *http.Request, status int, v any) {
+	c, body := negotiate(r, v)
+
+	varyAccept(w)
+	w.Header().Set("Content-Type", c.ContentType())
+	w.WriteHeader(status)
+	if err := c.Encode(w, body); err != nil {
+		logging.FromContext(r.Context()).Error("Error encoding response", "error", err, "content_type", c.ContentType())
+	}
+}
+
+func varyAccept(w http.ResponseWriter) {
+	if !slices.Contains(w.Header().Values("Vary"), "Accept") {
+		w.Header().Add("Vary", "Accept")
+	}
+}
+
+func negotiate(r *http.Request, v any) (codec.Codec, any) {
+	for _, c := range codec.Negotiate(r.Header.Get("Accept")) {
+		if c != codec.Protobuf {
+			return c, v
+		}
+		if m, ok := protoOf(v); ok {
+			return c, m
+		}
+	}
+	return codec.JSON, v
+}
+
+// protoOf maps response values to their chats.v1 message. Both API
+// versions share one protobuf shape.
+func protoOf(v any) (proto.Message, bool) {
+	switch v := v.(type) {
+	case *domain.Chat:
+		return protoconv.Chat(v), true
+	case chatV2:
+		return protoconv.Chat(v.chat), true
+	case *domain.Message:
+		return protoconv.Message(v), true
+	case []domain.Message:
+		return &chatsv1.ListMessagesResponse{Messages: protoconv.Messages(v)}, true
+	case messagePageV2:
+		return &chatsv1.ListMessagesResponse{Messages: protoconv.Messages(v.Data)}, true
+	}
+	return nil, false
+}
+
+// protoBody is implemented by request bodies that may also be sent as a
+// chats.v1 message: it returns the message to decode into and a function
+// copying the decoded fields back.
+type protoBody interface {
+	protoMessage() (proto.Message, func())
+}
+
+// decode reads the request body in the format named by Content-Type.
+func decode(r *http.Request, v any) error {
+	c, ok := codec.ForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		return errUnsupportedMediaType
+	}
+
+	target, apply := v, func() {}
+	if c == codec.Protobuf {
+		body, ok := v.(protoBody)
+		if !ok {
+			return errUnsupportedMediaType
+		}
+		target, apply = body.protoMessage()
+	}
+
+	if err := c.Decode(r.Body, target); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return problem.New(http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "")
+		}
+		return errInvalidBody
+	}
+	apply()
+	return nil
+}
+
+type createChatBody struct {
+	Title string `json:"title"`
+}
+
+func (b *createChatBody) protoMessage() (proto.Message, func()) {
+	m := &chatsv1.CreateChatRequest{}
+	return m, func() { b.Title = m.GetTitle() }
+}
+
+type updateChatBody struct {
+	Title string `json:"title"`
+}
+
+func (b *updateChatBody) protoMessage() (proto.Message, func()) {
+	m := &chatsv1.UpdateChatRequest{}
+	return m, func() { b.Title = m.GetTitle() }
+}
+
+type createMessageBody struct {
+	Text string `json:"text"`
+}
+
+func (b *createMessageBody) protoMessage() (proto.Message, func()) {
+	m := &chatsv1.CreateMessageRequest{}
+	return m, func() { b.Text = m.GetText() }
+}
+
+type updateMessageBody struct {
+	Text string `json:"text"`
+}
+
+func (b *updateMessageBody) protoMessage() (proto.Message, func()) {
+	m := &chatsv1.UpdateMessageRequest{}
+	return m, func() { b.Text = m.GetText() }
+}
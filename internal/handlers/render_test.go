@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	chatsv1 "chats/api/chats/v1"
+	"chats/internal/apiversion"
+	"chats/internal/codec"
+	"chats/internal/domain"
+	"chats/internal/problem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRender_Protobuf(t *testing.T) {
+	for _, version := range []apiversion.Version{apiversion.V1, apiversion.V2} {
+		t.Run(version.String(), func(t *testing.T) {
+			mockService := new(MockChatService)
+			handler := NewChatHandler(mockService)
+
+			chat := &domain.Chat{ID: 1, Title: "Чат", Version: 4, CreatedAt: time.Now(), Message: []domain.Message{{ID: 9, ChatID: 1, Seq: 1, Text: "Привет"}}}
+			mockService.On("GetChat", mock.Anything, uint(1), 20).Return(chat, nil)
+
+			req := httptest.NewRequest("GET", "/api/chats/1", nil)
+			req = req.WithContext(apiversion.WithVersion(req.Context(), version))
+			req.Header.Set("Accept", "application/x-protobuf")
+			rr := httptest.NewRecorder()
+
+			handler.HandleGetChat(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "application/x-protobuf", rr.Header().Get("Content-Type"))
+			var decoded chatsv1.Chat
+			require.NoError(t, proto.Unmarshal(rr.Body.Bytes(), &decoded))
+			assert.Equal(t, "Чат", decoded.GetTitle())
+			assert.Equal(t, uint64(4), decoded.GetVersion())
+			require.Len(t, decoded.GetMessages(), 1)
+			assert.Equal(t, "Привет", decoded.GetMessages()[0].GetText())
+		})
+	}
+}
+
+func TestRender_FallsBackWithoutProtobufMessage(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/admin/webhooks", nil)
+	req.Header.Set("Accept", "application/x-protobuf, application/msgpack;q=0.5")
+	rr := httptest.NewRecorder()
+
+	render(rr, req, http.StatusOK, []domain.Webhook{})
+
+	assert.Equal(t, "application/msgpack", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Values("Vary"), "Accept")
+}
+
+func TestDecode_MessagePackAndProtobuf(t *testing.T) {
+	mockService := new(MockChatService)
+	handler := NewChatHandler(mockService)
+	mockService.On("CreateChat", mock.Anything, "Чат").Return(&domain.Chat{ID: 1, Title: "Чат"}, nil)
+
+	var msgpackBody bytes.Buffer
+	require.NoError(t, codec.MessagePack.Encode(&msgpackBody, map[string]string{"title": "Чат"}))
+	protoBody, err := proto.Marshal(&chatsv1.CreateChatRequest{Title: "Чат"})
+	require.NoError(t, err)
+
+	for contentType, body := range map[string][]byte{
+		"application/msgpack":    msgpackBody.Bytes(),
+		"application/x-protobuf": protoBody,
+	} {
+		t.Run(contentType, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/chats", bytes.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Accept", contentType)
+			rr := httptest.NewRecorder()
+
+			handler.HandleCreateChat(rr, req)
+
+			assert.Equal(t, http.StatusCreated, rr.Code)
+			assert.Equal(t, contentType, rr.Header().Get("Content-Type"))
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "CreateChat", 2)
+}
+
+func TestDecode_UnsupportedMediaType(t *testing.T) {
+	handler := NewChatHandler(new(MockChatService))
+
+	req := httptest.NewRequest("POST", "/api/chats", bytes.NewBufferString("title=Чат"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCreateChat(rr, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"chats/internal/domain"
+	"chats/internal/helpers"
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"chats/internal/services"
+	"net/http"
+	"strings"
+)
+
+var errInvalidWebhookID = problem.New(http.StatusBadRequest, problem.CodeBadRequest, "Invalid webhook or delivery ID.")
+
+type WebhookHandler struct {
+	service services.WebhookService
+}
+
+func NewWebhookHandler(service services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+	}
+}
+
+func (h *WebhookHandler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	var request struct {
+		URL        string             `json:"url"`
+		ChatID     *uint              `json:"chat_id"`
+		EventTypes []domain.EventType `json:"event_types"`
+	}
+
+	if err := decode(r, &request); err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(r.Context(), strings.TrimSpace(request.URL), request.ChatID, request.EventTypes)
+	if err != nil {
+		logger.Warn("Error creating webhook", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	// The secret is only ever returned once, at creation time.
+	response := struct {
+		*domain.Webhook
+		Secret string `json:"secret"`
+	}{webhook, webhook.Secret}
+
+	render(w, r, http.StatusCreated, response)
+}
+
+func (h *WebhookHandler) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	webhooks, err := h.service.ListWebhooks(r.Context())
+	if err != nil {
+		logger.Error("Error listing webhooks", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	render(w, r, http.StatusOK, presentList(r, webhooks))
+}
+
+func (h *WebhookHandler) HandleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ParseIDParam(r, "id")
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidWebhookID)
+		return
+	}
+
+	webhook, err := h.service.GetWebhook(r.Context(), id)
+	if err != nil {
+		logger.Warn("Error getting webhook", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	render(w, r, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodDelete {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ParseIDParam(r, "id")
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidWebhookID)
+		return
+	}
+
+	if err := h.service.DeleteWebhook(r.Context(), id); err != nil {
+		logger.Warn("Error deleting webhook", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) HandleEnableWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ParseIDParam(r, "id")
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidWebhookID)
+		return
+	}
+
+	if err := h.service.EnableWebhook(r.Context(), id); err != nil {
+		logger.Warn("Error enabling webhook", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) HandleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := helpers.ParseIDParam(r, "id")
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidWebhookID)
+		return
+	}
+
+	limit := helpers.ParseLimitParam(r, 50, 500)
+	deliveries, err := h.service.ListDeliveries(r.Context(), id, limit)
+	if err != nil {
+		logger.Warn("Error listing deliveries", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	render(w, r, http.StatusOK, presentList(r, deliveries))
+}
+
+func (h *WebhookHandler) HandleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodGet {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, deliveryID, err := parseDeliveryPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidWebhookID)
+		return
+	}
+
+	delivery, err := h.service.GetDelivery(r.Context(), id, deliveryID)
+	if err != nil {
+		logger.Warn("Error getting delivery", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	render(w, r, http.StatusOK, delivery)
+}
+
+func (h *WebhookHandler) HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		logger.Warn("method not allowed", "method", r.Method)
+		problem.MethodNotAllowed(w, r)
+		return
+	}
+
+	id, deliveryID, err := parseDeliveryPath(r)
+	if err != nil {
+		logger.Warn("Bad Request", "error", err)
+		problem.Write(w, r, errInvalidWebhookID)
+		return
+	}
+
+	if err := h.service.Redeliver(r.Context(), id, deliveryID); err != nil {
+		logger.Warn("Error redelivering webhook", "error", err)
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func parseDeliveryPath(r *http.Request) (uint, uint, error) {
+	id, err := helpers.ParseIDParam(r, "id")
+	if err != nil {
+		return 0, 0, err
+	}
+	deliveryID, err := helpers.ParseIDParam(r, "deliveryID")
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, deliveryID, nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"chats/internal/domain"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) Publish(ctx context.Context, event domain.Event) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *MockWebhookService) CreateWebhook(ctx context.Context, url string, chatID *uint, eventTypes []domain.EventType) (*domain.Webhook, error) {
+	args := m.Called(ctx, url, chatID, eventTypes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) GetWebhook(ctx context.Context, id uint) (*domain.Webhook, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockWebhookService) EnableWebhook(ctx context.Context, id uint) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]domain.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookID, limit)
+	return args.Get(0).([]domain.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookService) GetDelivery(ctx context.Context, webhookID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookID, deliveryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookService) Redeliver(ctx context.Context, webhookID, deliveryID uint) error {
+	return m.Called(ctx, webhookID, deliveryID).Error(0)
+}
+
+func withURLParams(req *http.Request, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestWebhookHandler_HandleCreateWebhook_ReturnsSecretOnce(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	eventTypes := []domain.EventType{domain.EventMessageCreated}
+	created := &domain.Webhook{ID: 7, URL: "https://example.com/hook", EventTypes: eventTypes, Secret: "abc", Active: true}
+	mockService.On("CreateWebhook", mock.Anything, "https://example.com/hook", (*uint)(nil), eventTypes).Return(created, nil)
+
+	requestBody, _ := json.Marshal(map[string]any{"url": " https://example.com/hook ", "event_types": eventTypes})
+	req := httptest.NewRequest("POST", "/api/admin/webhooks", bytes.NewBuffer(requestBody))
+	rr := httptest.NewRecorder()
+
+	handler.HandleCreateWebhook(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "abc", response["secret"])
+	assert.Equal(t, float64(7), response["id"])
+
+	mockService.On("GetWebhook", mock.Anything, uint(7)).Return(created, nil)
+	req = withURLParams(httptest.NewRequest("GET", "/api/admin/webhooks/7", nil), map[string]string{"id": "7"})
+	rr = httptest.NewRecorder()
+
+	handler.HandleGetWebhook(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "abc")
+}
+
+func TestWebhookHandler_HandleCreateWebhook_InvalidInput(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	mockService.On("CreateWebhook", mock.Anything, "http://127.0.0.1/", (*uint)(nil), mock.Anything).
+		Return(nil, domain.ErrInvalidInput)
+
+	requestBody, _ := json.Marshal(map[string]any{"url": "http://127.0.0.1/", "event_types": []string{"chat.created"}})
+	req := httptest.NewRequest("POST", "/api/admin/webhooks", bytes.NewBuffer(requestBody))
+	rr := httptest.NewRecorder()
+
+	handler.HandleCreateWebhook(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestWebhookHandler_HandleRedeliver_NotFound(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	mockService.On("Redeliver", mock.Anything, uint(1), uint(99)).Return(domain.ErrNotFound)
+
+	req := httptest.NewRequest("POST", "/api/admin/webhooks/1/deliveries/99/redeliver", nil)
+	req = withURLParams(req, map[string]string{"id": "1", "deliveryID": "99"})
+	rr := httptest.NewRecorder()
+
+	handler.HandleRedeliver(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestWebhookHandler_HandleRedeliver_Disabled(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	mockService.On("Redeliver", mock.Anything, uint(1), uint(5)).Return(domain.ErrWebhookDisabled)
+
+	req := httptest.NewRequest("POST", "/api/admin/webhooks/1/deliveries/5/redeliver", nil)
+	req = withURLParams(req, map[string]string{"id": "1", "deliveryID": "5"})
+	rr := httptest.NewRecorder()
+
+	handler.HandleRedeliver(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "webhook is disabled")
+}
+
+func TestWebhookHandler_HandleRedeliver_Accepted(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	mockService.On("Redeliver", mock.Anything, uint(1), uint(5)).Return(nil)
+
+	req := httptest.NewRequest("POST", "/api/admin/webhooks/1/deliveries/5/redeliver", nil)
+	req = withURLParams(req, map[string]string{"id": "1", "deliveryID": "5"})
+	rr := httptest.NewRecorder()
+
+	handler.HandleRedeliver(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	mockService.AssertExpectations(t)
+}
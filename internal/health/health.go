@@ -0,0 +1,160 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Status values of a Report and of its checks.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	// StatusDraining reports a replica that is shutting down: it still
+	// serves in-flight requests but should get no new ones.
+	StatusDraining = "draining"
+)
+
+// Check reports whether a dependency is usable. It should return promptly
+// once ctx is done.
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is the readiness breakdown served by /health/ready.
+type Report struct {
+	Status    string                 `json:"status"`
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+// Ready reports whether the replica should receive traffic.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the registered checks in parallel, each under its own
+// timeout, and caches the report for a short while so frequent probes
+// from several sources don't hammer the dependencies.
+type Checker struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	checks   []namedCheck
+	cached   *Report
+	draining bool
+}
+
+// NewChecker returns a Checker that gives each check at most timeout and
+// reuses a report for cacheTTL; a zero cacheTTL disables caching.
+func NewChecker(timeout, cacheTTL time.Duration) *Checker {
+	return &Checker{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+	}
+}
+
+// Register adds a check under name. Registering after the first report
+// has been served is fine; the cache is dropped.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+	c.cached = nil
+}
+
+// SetDraining marks the replica as shutting down. From then on every
+// report is not ready, whatever the checks say.
+func (c *Checker) SetDraining() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.draining = true
+}
+
+// Report returns the cached report if it is fresh enough, and otherwise
+// runs all checks. Concurrent callers wait for a single run.
+func (c *Checker) Report(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.draining {
+		return Report{Status: StatusDraining, Checks: map[string]CheckResult{}, CheckedAt: now}
+	}
+	if c.cached != nil && now.Sub(c.cached.CheckedAt) < c.cacheTTL {
+		return *c.cached
+	}
+
+	report := c.run(ctx, now)
+	c.cached = &report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, now time.Time) Report {
+	results := make([]CheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.runOne(ctx, nc.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks)), CheckedAt: now}
+	for i, nc := range c.checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+func (c *Checker) runOne(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// A check that ignores ctx must not hold the probe up.
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = errors.New("timed out after " + c.timeout.String())
+		}
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
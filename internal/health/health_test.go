@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_AllChecksPass(t *testing.T) {
+	c := NewChecker(time.Second, 0)
+	c.Register("database", func(context.Context) error { return nil })
+	c.Register("migrations", func(context.Context) error { return nil })
+
+	report := c.Report(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Equal(t, StatusOK, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Empty(t, report.Checks["database"].Error)
+}
+
+func TestChecker_FailingCheckMakesItUnavailable(t *testing.T) {
+	c := NewChecker(time.Second, 0)
+	c.Register("database", func(context.Context) error { return errors.New("connection refused") })
+	c.Register("migrations", func(context.Context) error { return nil })
+
+	report := c.Report(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, CheckResult{Status: StatusUnavailable, Duration: report.Checks["database"].Duration, Error: "connection refused"}, report.Checks["database"])
+	assert.Equal(t, StatusOK, report.Checks["migrations"].Status)
+}
+
+func TestChecker_RunsChecksInParallelWithTimeouts(t *testing.T) {
+	c := NewChecker(50*time.Millisecond, 0)
+	block := make(chan struct{})
+	defer close(block)
+	for _, name := range []string{"a", "b", "c"} {
+		// Ignores ctx on purpose: the checker must not wait for it.
+		c.Register(name, func(context.Context) error {
+			<-block
+			return nil
+		})
+	}
+
+	start := time.Now()
+	report := c.Report(context.Background())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusUnavailable, report.Status)
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, "timed out after 50ms", report.Checks[name].Error)
+	}
+}
+
+func TestChecker_CachesReports(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	c := NewChecker(time.Second, time.Second)
+	c.now = func() time.Time { return now }
+	c.Register("database", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	c.Report(context.Background())
+	now = now.Add(500 * time.Millisecond)
+	c.Report(context.Background())
+	assert.Equal(t, int32(1), calls.Load())
+
+	now = now.Add(time.Second)
+	c.Report(context.Background())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestChecker_DrainingIsNotReady(t *testing.T) {
+	c := NewChecker(time.Second, time.Minute)
+	c.Register("database", func(context.Context) error { return nil })
+	require.True(t, c.Report(context.Background()).Ready())
+
+	c.SetDraining()
+	report := c.Report(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusDraining, report.Status)
+}
@@ -2,19 +2,34 @@ package helpers
 
 import (
 	"errors"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// ExtractIDFromPath returns the chat ID of /api[/vN]/chats/{id}/... paths. The
+// chi "id" parameter is used when the request was routed; otherwise the
+// segment after "chats" is parsed, so the path version prefix does not matter.
 func ExtractIDFromPath(r *http.Request) (uint, error) {
-	path := strings.Trim(r.URL.Path, "/")
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
+	raw := chi.URLParam(r, "id")
+	if raw == "" {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		for i := 0; i+1 < len(parts); i++ {
+			if parts[i] == "chats" {
+				raw = parts[i+1]
+				break
+			}
+		}
+	}
+	if raw == "" {
 		return 0, errors.New("invalid path format")
 	}
 
-	id, err := strconv.Atoi(parts[2])
+	id, err := strconv.Atoi(raw)
 
 	if err != nil || id <= 0 {
 		return 0, errors.New("invalid ID format")
@@ -44,3 +59,46 @@ func ParseLimitParam(r *http.Request, defaultValue, maxValue int) int {
 
 	return limit
 }
+
+func ParseIDParam(r *http.Request, name string) (uint, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid ID format")
+	}
+
+	return uint(id), nil
+}
+
+func ParseSeqParam(r *http.Request, name string) (uint64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	seq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid " + name)
+	}
+
+	return seq, nil
+}
+
+// Backoff returns the delay before the retry that follows the given attempt:
+// base * 2^(attempt-1), capped at maxDelay, with jitter in [d/2, d].
+func Backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	if base <= 0 {
+		base = time.Second
+	}
+	if maxDelay < base {
+		maxDelay = base
+	}
+
+	d := base
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
@@ -0,0 +1,19 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_GrowsAndCaps(t *testing.T) {
+	base := 100 * time.Millisecond
+	maxDelay := time.Second
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		got := Backoff(attempt, base, maxDelay)
+		assert.GreaterOrEqual(t, got, want/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, got, want, "attempt %d", attempt)
+	}
+}
@@ -0,0 +1,170 @@
+// Package lifecycle runs the server's components and shuts them down in
+// order, within a deadline, when the process is asked to stop.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+)
+
+type component struct {
+	name string
+	run  func() error // nil for components that only need stopping
+	stop func(ctx context.Context) error
+}
+
+// Manager starts components in the order they are added and stops them in
+// reverse order: add the database first and the servers last.
+type Manager struct {
+	timeout    time.Duration
+	components []component
+}
+
+// New returns a Manager that gives the whole shutdown at most timeout.
+func New(timeout time.Duration) *Manager {
+	return &Manager{timeout: timeout}
+}
+
+// Go adds a background worker that runs until its context is cancelled.
+// Stopping it cancels the context and waits for run to return.
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	m.components = append(m.components, component{
+		name: name,
+		run: func() error {
+			defer close(done)
+			run(ctx)
+			return nil
+		},
+		stop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
+
+// OnStop adds cleanup that has nothing to run, such as closing the database.
+func (m *Manager) OnStop(name string, stop func(ctx context.Context) error) {
+	m.components = append(m.components, component{name: name, stop: stop})
+}
+
+// ServeHTTP serves srv on l. Stopping it waits for in-flight requests until
+// the deadline.
+func (m *Manager) ServeHTTP(name string, srv *http.Server, l net.Listener) {
+	m.components = append(m.components, component{
+		name: name,
+		run: func() error {
+			if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				_ = srv.Close()
+				return err
+			}
+			return nil
+		},
+	})
+}
+
+// ServeGRPC serves srv on l. Stopping it reports NOT_SERVING through
+// healthServer and waits for running calls until the deadline.
+func (m *Manager) ServeGRPC(name string, srv *grpc.Server, healthServer *health.Server, l net.Listener) {
+	m.components = append(m.components, component{
+		name: name,
+		run: func() error {
+			return srv.Serve(l)
+		},
+		stop: func(ctx context.Context) error {
+			healthServer.Shutdown()
+
+			done := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// Run starts the components and blocks until ctx is done or one of them
+// fails, then stops all of them.
+func (m *Manager) Run(ctx context.Context) error {
+	logger := slog.Default()
+
+	failed := make(chan error, len(m.components))
+	var wg sync.WaitGroup
+	for _, c := range m.components {
+		if c.run == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.run(); err != nil {
+				failed <- fmt.Errorf("%s: %w", c.name, err)
+			}
+		}()
+	}
+
+	var cause error
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutting down", "reason", context.Cause(ctx))
+	case cause = <-failed:
+		logger.Error("Shutting down after a component failed", "error", cause)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	errs := []error{cause}
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c := m.components[i]
+		started := time.Now()
+		if err := c.stop(stopCtx); err != nil {
+			logger.Error("Error stopping component", "component", c.name, "error", err)
+			errs = append(errs, fmt.Errorf("stopping %s: %w", c.name, err))
+			continue
+		}
+		logger.Info("Stopped", "component", c.name, "took", time.Since(started))
+	}
+
+	// Components that did not stop in time are abandoned with the process.
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-stopCtx.Done():
+	}
+
+	return errors.Join(errs...)
+}
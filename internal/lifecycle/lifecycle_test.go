@@ -0,0 +1,128 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_StopsInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var stopped []string
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			stopped = append(stopped, name)
+			return nil
+		}
+	}
+
+	m := New(time.Second)
+	m.OnStop("database", record("database"))
+	workerStopped := make(chan struct{})
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		record("worker")(ctx)
+		close(workerStopped)
+	})
+	m.OnStop("hub", record("hub"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, m.Run(ctx))
+
+	<-workerStopped
+	assert.Equal(t, []string{"hub", "worker", "database"}, stopped)
+}
+
+func TestManager_ShutsDownWhenAComponentFails(t *testing.T) {
+	boom := errors.New("boom")
+	stopped := false
+
+	m := New(time.Second)
+	m.OnStop("database", func(context.Context) error {
+		stopped = true
+		return nil
+	})
+	m.components = append(m.components, component{
+		name: "server",
+		run:  func() error { return boom },
+		stop: func(context.Context) error { return nil },
+	})
+
+	err := m.Run(context.Background())
+
+	assert.ErrorIs(t, err, boom)
+	assert.True(t, stopped)
+}
+
+func TestManager_ReportsStopDeadline(t *testing.T) {
+	m := New(20 * time.Millisecond)
+	m.Go("stuck", func(ctx context.Context) {
+		time.Sleep(time.Second)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := m.Run(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestManager_ServeHTTPDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	m := New(5 * time.Second)
+	m.ServeHTTP("http", srv, l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- m.Run(ctx) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{string(body), err}
+	}()
+
+	<-started
+	cancel()
+	// Shutdown has closed the listener but waits for the running request.
+	require.Eventually(t, func() bool {
+		_, err := net.Dial("tcp", l.Addr().String())
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	close(release)
+
+	got := <-response
+	require.NoError(t, got.err)
+	assert.Equal(t, "done", got.body)
+	assert.NoError(t, <-runErr)
+}
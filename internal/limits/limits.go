@@ -0,0 +1,49 @@
+// Package limits holds the request limits shared by the HTTP, gRPC and
+// GraphQL APIs. They can change at runtime when the config is reloaded,
+// so callers read them with Current on every request.
+package limits
+
+import "sync/atomic"
+
+type Limits struct {
+	// MaxMessageLength and MaxTitleLength are in bytes.
+	MaxMessageLength int
+	MaxTitleLength   int
+	// DefaultHistory and MaxHistory bound the number of messages or chats
+	// returned when a limit is missing and at most.
+	DefaultHistory int
+	MaxHistory     int
+}
+
+// Default is in effect until Set is called.
+var Default = Limits{
+	MaxMessageLength: 5000,
+	MaxTitleLength:   200,
+	DefaultHistory:   20,
+	MaxHistory:       100,
+}
+
+var current atomic.Pointer[Limits]
+
+func init() {
+	Set(Default)
+}
+
+// Current returns the limits in effect.
+func Current() Limits {
+	return *current.Load()
+}
+
+// Set replaces the limits in effect.
+func Set(l Limits) {
+	current.Store(&l)
+}
+
+// ClampHistory returns DefaultHistory for a missing (zero or negative)
+// limit and caps the rest at MaxHistory.
+func (l Limits) ClampHistory(limit int) int {
+	if limit <= 0 {
+		return l.DefaultHistory
+	}
+	return min(limit, l.MaxHistory)
+}
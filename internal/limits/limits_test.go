@@ -0,0 +1,24 @@
+package limits
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClampHistory(t *testing.T) {
+	assert.Equal(t, 20, Default.ClampHistory(0))
+	assert.Equal(t, 20, Default.ClampHistory(-5))
+	assert.Equal(t, 50, Default.ClampHistory(50))
+	assert.Equal(t, 100, Default.ClampHistory(500))
+}
+
+func TestSet(t *testing.T) {
+	defer Set(Default)
+
+	assert.Equal(t, Default, Current())
+
+	Set(Limits{MaxMessageLength: 10, MaxTitleLength: 5, DefaultHistory: 2, MaxHistory: 3})
+	assert.Equal(t, 10, Current().MaxMessageLength)
+	assert.Equal(t, 3, Current().ClampHistory(50))
+}
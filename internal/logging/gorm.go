@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GORMOptions configure GORMLogger.
+type GORMOptions struct {
+	// Level is silent, error, warn or info: errors, then slow queries,
+	// then every statement (at debug level) are logged.
+	Level string
+	// SlowThreshold is the duration above which a query is logged as
+	// slow; zero disables slow-query logging.
+	SlowThreshold time.Duration
+	// LogParams renders query parameters into logged SQL. They may contain
+	// message texts, so they are left as placeholders by default.
+	LogParams bool
+}
+
+// GORMLogger routes GORM's logs through the request logger in the
+// statement's context, so SQL logs carry the request ID.
+func GORMLogger(opts GORMOptions) (gormlogger.Interface, error) {
+	level, err := parseGORMLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	return &gormLogger{level: level, slowThreshold: opts.SlowThreshold, logParams: opts.LogParams}, nil
+}
+
+func parseGORMLevel(s string) (gormlogger.LogLevel, error) {
+	switch s {
+	case "silent":
+		return gormlogger.Silent, nil
+	case "error":
+		return gormlogger.Error, nil
+	case "warn", "":
+		return gormlogger.Warn, nil
+	case "info":
+		return gormlogger.Info, nil
+	}
+	return 0, fmt.Errorf("unknown GORM log level %q", s)
+}
+
+type gormLogger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+	logParams     bool
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// ParamsFilter keeps parameters out of the SQL GORM renders for Trace
+// unless LogParams is set.
+func (l *gormLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if !l.logParams {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	logger := FromContext(ctx)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{"sql", sql, "rows", rows, "duration", elapsed}
+	}
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		logger.ErrorContext(ctx, "Query failed", append(attrs(), "error", err)...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		logger.WarnContext(ctx, "Slow query", append(attrs(), "threshold", l.slowThreshold)...)
+	case l.level >= gormlogger.Info && logger.Enabled(ctx, slog.LevelDebug):
+		logger.DebugContext(ctx, "Query", attrs()...)
+	}
+}
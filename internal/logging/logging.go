@@ -0,0 +1,86 @@
+// Package logging builds the process logger and carries a request-scoped
+// logger through contexts.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the values of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are never logged while redaction is on.
+var sensitiveKeys = map[string]bool{
+	"text":          true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+}
+
+// Options configure New.
+type Options struct {
+	// Env is Config.ENV: local and development log human-readable text,
+	// anything else JSON for log collectors.
+	Env string
+	// Level may be a *slog.LevelVar so it can change at runtime.
+	Level slog.Leveler
+	// Redact replaces the values of sensitive attributes, such as message
+	// texts, with Redacted.
+	Redact bool
+}
+
+// New returns a logger writing to w in the format for opts.Env.
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	if opts.Redact {
+		handlerOpts.ReplaceAttr = redact
+	}
+
+	if IsText(opts.Env) {
+		return slog.New(slog.NewTextHandler(w, handlerOpts))
+	}
+	return slog.New(slog.NewJSONHandler(w, handlerOpts))
+}
+
+// IsText reports whether env logs text rather than JSON.
+func IsText(env string) bool {
+	switch strings.ToLower(env) {
+	case "local", "dev", "development":
+		return true
+	}
+	return false
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] && a.Value.Kind() != slog.KindGroup {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// ParseLevel maps debug, info, warn and error to their slog levels.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, which carries the request
+// ID and trace of the request it belongs to, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestNew_FormatFollowsEnv(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, Options{Env: "production", Level: slog.LevelInfo}).Info("hello", "chat_id", 1)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "hello", entry["msg"])
+	assert.Equal(t, 1.0, entry["chat_id"])
+
+	buf.Reset()
+	New(&buf, Options{Env: "local", Level: slog.LevelInfo}).Info("hello", "chat_id", 1)
+	assert.Contains(t, buf.String(), "msg=hello chat_id=1")
+}
+
+func TestNew_RedactsSensitiveAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Env: "production", Redact: true})
+	logger.Info("message created", "text", "meet me at noon", "Authorization", "Bearer abc", slog.Group("webhook", "secret", "s3cr3t", "id", 4))
+
+	out := buf.String()
+	assert.NotContains(t, out, "noon")
+	assert.NotContains(t, out, "abc")
+	assert.NotContains(t, out, "s3cr3t")
+	assert.Contains(t, out, `"text":"[REDACTED]"`)
+	assert.Contains(t, out, `"webhook":{"secret":"[REDACTED]","id":4}`)
+
+	buf.Reset()
+	New(&buf, Options{Env: "production"}).Info("message created", "text", "meet me at noon")
+	assert.Contains(t, buf.String(), "noon")
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	logger := New(&buf, Options{Env: "production", Level: level})
+
+	logger.Info("dropped")
+	assert.Empty(t, buf.String())
+
+	level.Set(slog.LevelInfo)
+	logger.Info("kept")
+	assert.Contains(t, buf.String(), "kept")
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	assert.Same(t, logger, FromContext(WithLogger(context.Background(), logger)))
+}
+
+func TestGORMLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), New(&buf, Options{Env: "production", Level: slog.LevelDebug}).With("request_id", "req-1"))
+	sql := func() (string, int64) { return `SELECT * FROM "messages" WHERE chat_id = $1`, 3 }
+
+	l, err := GORMLogger(GORMOptions{Level: "warn", SlowThreshold: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	l.Trace(ctx, time.Now(), sql, nil)
+	assert.Empty(t, buf.String(), "fast queries are not logged at warn")
+
+	l.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+	assert.Empty(t, buf.String(), "not found is not an error")
+
+	l.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+	assert.Contains(t, buf.String(), `"msg":"Slow query"`)
+	assert.Contains(t, buf.String(), `"request_id":"req-1"`)
+	assert.Contains(t, buf.String(), `"rows":3`)
+
+	buf.Reset()
+	l.Trace(ctx, time.Now(), sql, errors.New("deadlock detected"))
+	assert.Contains(t, buf.String(), `"level":"ERROR"`)
+	assert.Contains(t, buf.String(), "deadlock detected")
+
+	buf.Reset()
+	l.LogMode(gormlogger.Info).Trace(ctx, time.Now(), sql, nil)
+	assert.Contains(t, buf.String(), `"level":"DEBUG"`)
+
+	_, err = GORMLogger(GORMOptions{Level: "verbose"})
+	assert.Error(t, err)
+}
+
+func TestGORMLogger_KeepsParamsOutOfSQL(t *testing.T) {
+	l, err := GORMLogger(GORMOptions{})
+	require.NoError(t, err)
+	filter := l.(gorm.ParamsFilter)
+
+	sql, params := filter.ParamsFilter(context.Background(), "INSERT INTO messages (text) VALUES ($1)", "meet me at noon")
+	assert.Equal(t, "INSERT INTO messages (text) VALUES ($1)", sql)
+	assert.Empty(t, params)
+
+	l, err = GORMLogger(GORMOptions{LogParams: true})
+	require.NoError(t, err)
+	_, params = l.(gorm.ParamsFilter).ParamsFilter(context.Background(), "INSERT INTO messages (text) VALUES ($1)", "meet me at noon")
+	assert.Equal(t, []any{"meet me at noon"}, params)
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// GORMPlugin times every statement GORM runs, by operation and table.
+func (m *Metrics) GORMPlugin() gorm.Plugin {
+	return gormPlugin{metrics: m}
+}
+
+type gormPlugin struct {
+	metrics *Metrics
+}
+
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	before := func(db *gorm.DB) {
+		db.InstanceSet(startKey, time.Now())
+	}
+
+	return errors.Join(
+		cb.Create().Before("*").Register("metrics:before_create", before),
+		cb.Create().After("*").Register("metrics:after_create", p.after("create")),
+		cb.Query().Before("*").Register("metrics:before_query", before),
+		cb.Query().After("*").Register("metrics:after_query", p.after("query")),
+		cb.Update().Before("*").Register("metrics:before_update", before),
+		cb.Update().After("*").Register("metrics:after_update", p.after("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", before),
+		cb.Delete().After("*").Register("metrics:after_delete", p.after("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", before),
+		cb.Row().After("*").Register("metrics:after_row", p.after("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", before),
+		cb.Raw().After("*").Register("metrics:after_raw", p.after("raw")),
+	)
+}
+
+func (p gormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+
+		// Tables come from the schema, so they are a closed set; raw SQL
+		// has none.
+		table := db.Statement.Table
+		if table == "" {
+			table = "none"
+		}
+		p.metrics.dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
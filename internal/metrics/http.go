@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing
+// random paths add no series.
+const unmatchedRoute = "unmatched"
+
+// Middleware counts and times requests by the chi route pattern they
+// matched, e.g. /api/chats/{id}, not by their raw path. It must wrap the
+// router: the pattern is only known once routing is done.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			route := routePattern(r)
+			method := methodLabel(r.Method)
+			status := ww.Status()
+			switch {
+			case status != 0:
+			case r.Header.Get("Upgrade") != "":
+				// Hijacked for a websocket; the 101 bypassed the writer.
+				status = http.StatusSwitchingProtocols
+			default:
+				// Nothing was written, so net/http answers 200.
+				status = http.StatusOK
+			}
+
+			m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+			m.httpDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return unmatchedRoute
+	}
+	pattern := rctx.RoutePattern()
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	// Subrouters mounted at "/" leave a trailing slash: /api/chats/{id}/.
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"chats/internal/domain"
+	"chats/internal/services"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Route("/api/chats/{id}", func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/chats/1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/chats/2", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/chats/3", nil),
+		httptest.NewRequest(http.MethodGet, "/wp-admin/login.php", nil),
+		httptest.NewRequest("PROPFIND", "/api/chats/4", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/api/chats/{id}", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/api/chats/{id}", "DELETE", "204")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(unmatchedRoute, "GET", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(unmatchedRoute, "OTHER", "405")))
+	assert.Equal(t, 4, testutil.CollectAndCount(m.httpRequests))
+	assert.Equal(t, 4, testutil.CollectAndCount(m.httpDuration))
+}
+
+type stubMessageService struct {
+	services.MessageService
+	err error
+}
+
+func (s stubMessageService) CreateMessage(ctx context.Context, chatID uint, text string) (*domain.Message, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &domain.Message{ChatID: chatID, Text: text}, nil
+}
+
+func TestMessageService_CountsCreatedAndRejected(t *testing.T) {
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{domain.ErrEmptyText, ReasonEmptyText},
+		{domain.ErrTextTooLong, ReasonTooLong},
+		{fmt.Errorf("chat 7: %w", domain.ErrNotFound), ReasonChatNotFound},
+		{context.Canceled, ReasonCancelled},
+		{io.ErrUnexpectedEOF, ReasonError},
+	}
+
+	m := New()
+	_, err := m.MessageService(stubMessageService{}).CreateMessage(context.Background(), 1, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesCreated))
+
+	for _, tt := range tests {
+		_, err := m.MessageService(stubMessageService{err: tt.err}).CreateMessage(context.Background(), 1, "hi")
+		assert.ErrorIs(t, err, tt.err)
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesRejected.WithLabelValues(tt.reason)), tt.reason)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesCreated))
+}
+
+func TestGORMPlugin_ObservesStatements(t *testing.T) {
+	m := New()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(m.GORMPlugin()))
+
+	db.Create(&domain.Chat{Title: "general"})
+	db.First(&domain.Chat{}, 1)
+	db.First(&domain.Chat{}, 2)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.dbQueryDuration))
+	body := scrape(t, m)
+	assert.Contains(t, body, `chats_db_query_duration_seconds_count{operation="create",table="chats"} 1`)
+	assert.Contains(t, body, `chats_db_query_duration_seconds_count{operation="query",table="chats"} 2`)
+}
+
+func TestHandler_ServesPoolStats(t *testing.T) {
+	m := New()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, m.RegisterDB(sqlDB, "chats"))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="chats"} 0`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"chats/internal/domain"
+	"chats/internal/services"
+	"context"
+	"errors"
+)
+
+// Reasons a message is rejected, the values of messages_rejected_total's
+// reason label.
+const (
+	ReasonEmptyText    = "empty_text"
+	ReasonTooLong      = "too_long"
+	ReasonChatNotFound = "chat_not_found"
+	ReasonCancelled    = "cancelled"
+	ReasonError        = "error"
+)
+
+// ChatService counts the chats created through s, whichever API they
+// come from.
+func (m *Metrics) ChatService(s services.ChatService) services.ChatService {
+	return &chatService{ChatService: s, metrics: m}
+}
+
+// MessageService counts the messages created through s and rejected by it.
+func (m *Metrics) MessageService(s services.MessageService) services.MessageService {
+	return &messageService{MessageService: s, metrics: m}
+}
+
+type chatService struct {
+	services.ChatService
+	metrics *Metrics
+}
+
+func (s *chatService) CreateChat(ctx context.Context, title string) (*domain.Chat, error) {
+	chat, err := s.ChatService.CreateChat(ctx, title)
+	if err == nil {
+		s.metrics.chatsCreated.Inc()
+	}
+	return chat, err
+}
+
+type messageService struct {
+	services.MessageService
+	metrics *Metrics
+}
+
+func (s *messageService) CreateMessage(ctx context.Context, chatID uint, text string) (*domain.Message, error) {
+	message, err := s.MessageService.CreateMessage(ctx, chatID, text)
+	if err != nil {
+		s.metrics.messagesRejected.WithLabelValues(rejectReason(err)).Inc()
+		return nil, err
+	}
+	s.metrics.messagesCreated.Inc()
+	return message, nil
+}
+
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrEmptyText):
+		return ReasonEmptyText
+	case errors.Is(err, domain.ErrTextTooLong):
+		return ReasonTooLong
+	case errors.Is(err, domain.ErrNotFound):
+		return ReasonChatNotFound
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ReasonCancelled
+	default:
+		return ReasonError
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RequireAdmin only lets through requests carrying "Authorization: Bearer <token>".
+// With an empty token the admin API is switched off entirely.
+func RequireAdmin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p := CheckAdmin(r, token); p != nil {
+				if p.Status == http.StatusUnauthorized {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				}
+				problem.Write(w, r, p)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CheckAdmin is the check behind RequireAdmin, for callers that authorize
+// parts of a request separately. It returns nil when r carries the token.
+func CheckAdmin(r *http.Request, token string) *problem.Problem {
+	if token == "" {
+		return problem.New(http.StatusForbidden, problem.CodeForbidden, "The admin API is disabled.")
+	}
+
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		logging.FromContext(r.Context()).Warn("Unauthorized admin request", "path", r.URL.Path, "remote", r.RemoteAddr)
+		return problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "")
+	}
+	return nil
+}
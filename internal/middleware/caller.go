@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// CallerID identifies who sent the request: a digest of the Authorization
+// header when present, otherwise the client IP. Raw credentials never leave it.
+// Behind a load balancer the client IP is only right with RealIP in front.
+func CallerID(r *http.Request) string {
+	return CallerIDFrom(r.Header.Get("Authorization"), r.RemoteAddr)
+}
+
+// CallerIDFrom is CallerID for other transports, given the credentials and
+// the peer address they came with.
+func CallerIDFrom(authorization, remoteAddr string) string {
+	if authorization != "" {
+		sum := sha256.Sum256([]byte(authorization))
+		return "auth:" + hex.EncodeToString(sum[:16])
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
+
+// RealIP replaces RemoteAddr of requests that came through one of the trusted
+// proxies with the client address from X-Forwarded-For: the rightmost entry
+// that is not itself a trusted proxy, as those further left are up to the
+// client. Requests from other peers keep RemoteAddr, whatever they send.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		return slices.ContainsFunc(trusted, func(p netip.Prefix) bool { return p.Contains(addr) })
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddrPort(r.RemoteAddr)
+			if err != nil || !isTrusted(peer.Addr()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+				if err != nil {
+					break
+				}
+				if !isTrusted(addr) {
+					r.RemoteAddr = netip.AddrPortFrom(addr.Unmap(), 0).String()
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"untrusted peer", "203.0.113.7:1234", "198.51.100.1", "ip:203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:1234", "198.51.100.1", "ip:198.51.100.1"},
+		{"spoofed entries are skipped", "10.0.0.1:1234", "1.2.3.4, 198.51.100.1", "ip:198.51.100.1"},
+		{"chained proxies", "10.0.0.1:1234", "198.51.100.1, 10.0.0.2", "ip:198.51.100.1"},
+		{"no header", "10.0.0.1:1234", "", "ip:10.0.0.1"},
+		{"garbage", "10.0.0.1:1234", "unknown", "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = CallerID(r)
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCallerIDFrom(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	assert.Equal(t, CallerID(req), CallerIDFrom("", "203.0.113.7:5678"), "the port does not matter")
+
+	req.Header.Set("Authorization", "Bearer token")
+	assert.Equal(t, CallerID(req), CallerIDFrom("Bearer token", "198.51.100.1:1"))
+	assert.NotContains(t, CallerIDFrom("Bearer token", ""), "token")
+}
@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compress encodes response bodies of at least minSize bytes with zstd or
+// gzip, whichever Accept-Encoding prefers (zstd on a tie). Smaller bodies
+// are sent as they are. A handler that flushes is streaming, so its
+// response is compressed from the first flush on whatever its size.
+// An encoded response is a representation of its own, so its ETag gets
+// the encoding as a suffix, which is taken off If-None-Match again.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Upgraded connections (GraphQL websockets) need the raw writer.
+			if r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			if header := r.Header.Get("If-None-Match"); header != "" {
+				plain := strings.ReplaceAll(header, "-"+encoding+`"`, `"`)
+				cw.revalidated = plain != header
+				r.Header.Set("If-None-Match", plain)
+			}
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// acceptedEncoding returns "zstd", "gzip" or "" for the Accept-Encoding header.
+func acceptedEncoding(header string) string {
+	q := map[string]float64{}
+	wildcard := -1.0
+	for part := range strings.SplitSeq(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		if name == "*" {
+			wildcard = weight
+			continue
+		}
+		q[name] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range []string{"zstd", "gzip"} {
+		weight, ok := q[name]
+		if !ok {
+			weight = wildcard
+		}
+		if weight > bestQ {
+			best, bestQ = name, weight
+		}
+	}
+	return best
+}
+
+// incompressible lists content types that are already compressed.
+var incompressible = []string{"image/", "video/", "audio/", "application/zip", "application/gzip", "application/zstd"}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType == ""
+	}
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+	for _, prefix := range incompressible {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoders = map[string]*sync.Pool{
+	"gzip": {New: func() any {
+		return encoder(gzip.NewWriter(nil))
+	}},
+	"zstd": {New: func() any {
+		// Responses are small and many run at once, so one goroutine each.
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return encoder(enc)
+	}},
+}
+
+// compressWriter buffers the body until it reaches minSize or is flushed
+// and only then decides whether to compress, as headers go out with that
+// decision.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+	// revalidated is set when If-None-Match named an encoded ETag, which
+	// a 304 then confirms.
+	revalidated bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		_ = cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		if len(cw.buf)+len(p) < cw.minSize {
+			cw.buf = append(cw.buf, p...)
+			return len(p), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide writes the headers, compressing when asked to and the response
+// is not encoded already, then writes out what was buffered.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+
+	h := cw.Header()
+	if compress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		cw.enc = encoders[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+		cw.tagETag()
+	} else if cw.status == http.StatusNotModified && cw.revalidated {
+		cw.tagETag()
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) tagETag() {
+	h := cw.Header()
+	if etag := h.Get("ETag"); strings.HasSuffix(etag, `"`) {
+		h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+cw.encoding+`"`)
+	}
+}
+
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 {
+			// Nothing was written; let net/http send its implicit 200.
+			return
+		}
+		_ = cw.decide(false)
+	}
+	if cw.enc == nil {
+		return
+	}
+	_ = cw.enc.Close()
+	cw.enc.Reset(nil)
+	encoders[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"gzip":                   "gzip",
+		"gzip, zstd":             "zstd",
+		"zstd;q=0.5, gzip":       "gzip",
+		"br":                     "",
+		"*":                      "zstd",
+		"*, zstd;q=0":            "gzip",
+		"gzip;q=0, zstd;q=0":     "",
+		"identity, gzip;q=0.001": "gzip",
+	}
+	for header, want := range tests {
+		assert.Equal(t, want, acceptedEncoding(header), header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"text": "привет"}`, 200)
+	handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		body := large
+		if r.URL.Query().Has("small") {
+			body = "{}"
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+
+	serve := func(target, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		rr := serve("/", "gzip")
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+		assert.Contains(t, rr.Header().Values("Vary"), "Accept-Encoding")
+
+		reader, err := gzip.NewReader(rr.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		rr := serve("/", "gzip, zstd")
+		assert.Equal(t, "zstd", rr.Header().Get("Content-Encoding"))
+
+		decoder, err := zstd.NewReader(bytes.NewReader(rr.Body.Bytes()))
+		require.NoError(t, err)
+		defer decoder.Close()
+		body, err := io.ReadAll(decoder)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		rr := serve("/?small", "gzip")
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, "{}", rr.Body.String())
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		rr := serve("/", "")
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, rr.Body.String())
+	})
+}
+
+func TestCompress_FlushStartsCompressing(t *testing.T) {
+	handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "first,")
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, "second")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.True(t, rr.Flushed)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "first,second", string(body))
+}
+
+func TestCompress_ETag(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+	handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"3-1-json"`)
+		if r.Header.Get("If-None-Match") == `"3-1-json"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, large)
+	}))
+
+	serve := func(acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("gzip", "")
+	assert.Equal(t, `"3-1-json-gzip"`, rr.Header().Get("ETag"), "the encoded body has an ETag of its own")
+
+	rr = serve("gzip", `"3-1-json-gzip"`)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Equal(t, `"3-1-json-gzip"`, rr.Header().Get("ETag"))
+
+	rr = serve("", `"3-1-json-gzip"`)
+	assert.Equal(t, http.StatusOK, rr.Code, "the identity body is another representation")
+	assert.Equal(t, `"3-1-json"`, rr.Header().Get("ETag"))
+
+	rr = serve("zstd", `"3-1-json"`)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Equal(t, `"3-1-json"`, rr.Header().Get("ETag"))
+}
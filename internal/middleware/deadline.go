@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Deadline gives each request at most timeout, so every statement it runs
+// shares one deadline. Upgraded connections, which live as long as the
+// client keeps them, get none. Zero disables the deadline.
+func Deadline(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"chats/internal/apiversion"
+	"chats/internal/domain"
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"chats/internal/repositories"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// Idempotency replays the stored response when a caller repeats a request
+// with the same Idempotency-Key and payload. Reusing a key for a different
+// payload is rejected with 422; a key whose first request is still running
+// gets 409 until lockTimeout passes, after which the claim may be taken over.
+// Server errors are not stored so the client can retry them.
+func Idempotency(repo repositories.IdempotencyRepository, ttl, lockTimeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger := logging.FromContext(r.Context())
+
+			if len(key) > maxIdempotencyKeyLength {
+				problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, "Idempotency-Key is too long"))
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBytes+1))
+			if err != nil {
+				problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, "The request body could not be read."))
+				return
+			}
+			if len(body) > maxIdempotentRequestBytes {
+				problem.Write(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, ""))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record := &domain.IdempotencyRecord{
+				Scope:       CallerID(r),
+				Key:         key,
+				RequestHash: requestHash(r, body),
+				// Truncated to what the database stores, as Complete and
+				// Release match the claim by it.
+				LockedUntil: time.Now().Add(lockTimeout).Truncate(time.Microsecond),
+				ExpiresAt:   time.Now().Add(ttl),
+			}
+
+			stored, claimed, err := repo.Claim(r.Context(), record)
+			if err != nil {
+				problem.Error(w, r, fmt.Errorf("claim idempotency key: %w", err))
+				return
+			}
+
+			if !claimed {
+				switch {
+				case stored.RequestHash != record.RequestHash:
+					problem.Write(w, r, problem.New(http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request"))
+				case !stored.Completed():
+					problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeIdempotencyKeyInProgress, "A request with this Idempotency-Key is still in progress"))
+				default:
+					for name, values := range stored.ResponseHeaders {
+						w.Header()[name] = values
+					}
+					if len(stored.ResponseHeaders) == 0 && stored.ContentType != "" {
+						w.Header().Set("Content-Type", stored.ContentType)
+					}
+					w.Header().Set(IdempotentReplayedHeader, "true")
+					w.WriteHeader(stored.StatusCode)
+					_, _ = w.Write(stored.ResponseBody)
+				}
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := repo.Release(context.WithoutCancel(r.Context()), record); err != nil {
+					logger.Error("Error releasing idempotency key", "error", err)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+
+			record.StatusCode = rec.status
+			record.ResponseHeaders = rec.header
+			record.ResponseBody = rec.body.Bytes()
+			err = repo.Complete(context.WithoutCancel(r.Context()), record)
+			if err != nil {
+				logger.Error("Error storing idempotent response", "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// RunIdempotencyJanitor deletes expired keys every interval until ctx is done.
+func RunIdempotencyJanitor(ctx context.Context, repo repositories.IdempotencyRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := repo.DeleteExpired(ctx); err != nil {
+				slog.Default().Error("Error deleting expired idempotency keys", "error", err)
+			}
+		}
+	}
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Header.Get("Accept")))
+	h.Write([]byte{0})
+	h.Write([]byte(apiversion.FromContext(r.Context()).String()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// unreplayedHeaders are set per response, or by the middleware itself.
+var unreplayedHeaders = []string{"Content-Length", "Content-Encoding", "Date", chimw.RequestIDHeader, IdempotentReplayedHeader}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      domain.Header
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.header = domain.Header(r.Header().Clone())
+		for _, name := range unreplayedHeaders {
+			delete(r.header, name)
+		}
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"chats/internal/domain"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}
+}
+
+func (m *memoryIdempotencyRepository) Claim(_ context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := record.Scope + "|" + record.Key
+	existing, ok := m.records[id]
+	abandoned := !existing.Completed() && !existing.LockedUntil.After(time.Now())
+	if ok && existing.ExpiresAt.After(time.Now()) && !abandoned {
+		return &existing, false, nil
+	}
+	m.records[id] = *record
+	return record, true, nil
+}
+
+func (m *memoryIdempotencyRepository) claimed(record *domain.IdempotencyRecord) bool {
+	existing, ok := m.records[record.Scope+"|"+record.Key]
+	return ok && !existing.Completed() && existing.LockedUntil.Equal(record.LockedUntil)
+}
+
+func (m *memoryIdempotencyRepository) Complete(_ context.Context, record *domain.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.claimed(record) {
+		m.records[record.Scope+"|"+record.Key] = *record
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) Release(_ context.Context, record *domain.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.claimed(record) {
+		delete(m.records, record.Scope+"|"+record.Key)
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) DeleteExpired(context.Context) (int64, error) { return 0, nil }
+
+func countingHandler(calls *int, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/chats/"+strconv.Itoa(*calls))
+		w.Header().Set("ETag", `"`+strconv.Itoa(*calls)+`"`)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"call":` + strconv.Itoa(*calls) + `,"echo":` + string(body) + `}`))
+	})
+}
+
+func send(h http.Handler, key, remote, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/chats", strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	req.RemoteAddr = remote
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	calls := 0
+	h := Idempotency(newMemoryIdempotencyRepository(), time.Hour, time.Minute)(countingHandler(&calls, http.StatusCreated))
+
+	first := send(h, "key-1", "10.0.0.1:1234", `{"title":"a"}`)
+	second := send(h, "key-1", "10.0.0.1:5678", `{"title":"a"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, "/api/chats/1", second.Header().Get("Location"))
+	assert.Equal(t, `"1"`, second.Header().Get("ETag"))
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotency_RejectsDifferentPayload(t *testing.T) {
+	calls := 0
+	h := Idempotency(newMemoryIdempotencyRepository(), time.Hour, time.Minute)(countingHandler(&calls, http.StatusCreated))
+
+	send(h, "key-1", "10.0.0.1:1", `{"title":"a"}`)
+	rr := send(h, "key-1", "10.0.0.1:1", `{"title":"b"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_KeysAreScopedPerCaller(t *testing.T) {
+	calls := 0
+	h := Idempotency(newMemoryIdempotencyRepository(), time.Hour, time.Minute)(countingHandler(&calls, http.StatusCreated))
+
+	send(h, "key-1", "10.0.0.1:1", `{"title":"a"}`)
+	rr := send(h, "key-1", "10.0.0.2:1", `{"title":"a"}`)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_DoesNotStoreServerErrors(t *testing.T) {
+	calls := 0
+	h := Idempotency(newMemoryIdempotencyRepository(), time.Hour, time.Minute)(countingHandler(&calls, http.StatusInternalServerError))
+
+	send(h, "key-1", "10.0.0.1:1", `{}`)
+	send(h, "key-1", "10.0.0.1:1", `{}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_WithoutKeyPassesThrough(t *testing.T) {
+	calls := 0
+	h := Idempotency(newMemoryIdempotencyRepository(), time.Hour, time.Minute)(countingHandler(&calls, http.StatusCreated))
+
+	send(h, "", "10.0.0.1:1", `{}`)
+	send(h, "", "10.0.0.1:1", `{}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_HashCoversAccept(t *testing.T) {
+	calls := 0
+	h := Idempotency(newMemoryIdempotencyRepository(), time.Hour, time.Minute)(countingHandler(&calls, http.StatusCreated))
+
+	send(h, "key-1", "10.0.0.1:1", `{}`, "Accept", "application/json")
+	rr := send(h, "key-1", "10.0.0.1:1", `{}`, "Accept", "application/x-protobuf")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_TakesOverAbandonedClaim(t *testing.T) {
+	repo := newMemoryIdempotencyRepository()
+	calls := 0
+	h := Idempotency(repo, time.Hour, time.Minute)(countingHandler(&calls, http.StatusCreated))
+
+	// The request that claimed the key crashed before completing it.
+	req := httptest.NewRequest("POST", "/api/chats", strings.NewReader(`{}`))
+	req.RemoteAddr = "10.0.0.1:1"
+	_, claimed, _ := repo.Claim(context.Background(), &domain.IdempotencyRecord{
+		Scope:       CallerID(req),
+		Key:         "key-1",
+		RequestHash: requestHash(req, []byte(`{}`)),
+		LockedUntil: time.Now().Add(time.Minute),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	assert.True(t, claimed)
+
+	rr := send(h, "key-1", "10.0.0.1:1", `{}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, "the claim is held until it times out")
+
+	repo.mu.Lock()
+	record := repo.records[CallerID(req)+"|key-1"]
+	record.LockedUntil = time.Now().Add(-time.Second)
+	repo.records[CallerID(req)+"|key-1"] = record
+	repo.mu.Unlock()
+
+	rr = send(h, "key-1", "10.0.0.1:1", `{}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, send(h, "key-1", "10.0.0.1:1", `{}`).Code)
+	assert.Equal(t, 1, calls, "the taken over claim was completed")
+}
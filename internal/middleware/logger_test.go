@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"chats/internal/logging"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		honored  bool
+	}{
+		{name: "honors incoming", incoming: "edge-7f3a:42", honored: true},
+		{name: "generates when missing"},
+		{name: "replaces too long", incoming: strings.Repeat("a", 129)},
+		{name: "replaces unsafe characters", incoming: "abc\n level=ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = chimw.GetReqID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, seen, rr.Header().Get("X-Request-Id"))
+			if tt.honored {
+				assert.Equal(t, tt.incoming, seen)
+			} else {
+				assert.Len(t, seen, 32)
+			}
+		})
+	}
+}
+
+func TestLogger_ScopesLoggerAndLogsAccess(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	r := chi.NewRouter()
+	r.Use(RequestID, Logger)
+	r.Get("/api/chats/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("Loading chat")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("{}"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chats/9", nil)
+	req.Header.Set("X-Request-ID", "req-9")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var handlerLine, accessLine map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &handlerLine))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &accessLine))
+
+	assert.Equal(t, "Loading chat", handlerLine["msg"])
+	assert.Equal(t, "req-9", handlerLine["request_id"])
+
+	assert.Equal(t, "Request", accessLine["msg"])
+	assert.Equal(t, "req-9", accessLine["request_id"])
+	assert.Equal(t, "/api/chats/{id}", accessLine["route"])
+	assert.Equal(t, "/api/chats/9", accessLine["path"])
+	assert.Equal(t, 404.0, accessLine["status"])
+	assert.Equal(t, 2.0, accessLine["bytes"])
+}
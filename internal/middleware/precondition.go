@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"chats/internal/problem"
+	"net/http"
+)
+
+// RequireIfMatch rejects requests without If-Match with 428, so clients in
+// strict mode cannot overwrite changes they have never seen. "If-Match: *"
+// still opts out explicitly.
+func RequireIfMatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") == "" {
+			problem.Write(w, r, problem.New(http.StatusPreconditionRequired, problem.CodePreconditionRequired,
+				"Send If-Match with the ETag of the version you are changing."))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
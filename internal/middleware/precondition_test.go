@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"chats/internal/problem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireIfMatch(t *testing.T) {
+	handler := RequireIfMatch(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/chats/1", nil))
+	assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+	assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+
+	for _, ifMatch := range []string{`"3-1"`, "*"} {
+		req := httptest.NewRequest("DELETE", "/api/chats/1", nil)
+		req.Header.Set("If-Match", ifMatch)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code, ifMatch)
+	}
+}
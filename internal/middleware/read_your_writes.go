@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"chats/internal/database"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minStickySweep is the number of remembered callers below which expired
+// ones are not swept.
+const minStickySweep = 1024
+
+// ReadYourWrites keeps each caller, identified by CallerID, on the primary
+// database while s says so.
+func ReadYourWrites(s *Stickiness) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(s.Session(r.Context(), CallerID(r))))
+		})
+	}
+}
+
+// Stickiness remembers until when each caller stays on the primary: for
+// window after its last write, so reads that follow it, possibly on another
+// connection or through another API, are not served by a replica that has
+// not caught up yet.
+type Stickiness struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	until   map[string]time.Time
+	sweepAt int
+}
+
+func NewStickiness(window time.Duration) *Stickiness {
+	return &Stickiness{window: window, until: make(map[string]time.Time), sweepAt: minStickySweep}
+}
+
+// Session returns ctx with a database session for caller. Reads in the
+// same session as a write go to the primary too.
+func (s *Stickiness) Session(ctx context.Context, caller string) context.Context {
+	return database.ReadYourWrites(ctx, s.sticky(caller), func() {
+		s.wrote(caller)
+	})
+}
+
+func (s *Stickiness) sticky(caller string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.until[caller]
+	if ok && time.Now().After(until) {
+		delete(s.until, caller)
+		return false
+	}
+	return ok
+}
+
+func (s *Stickiness) wrote(caller string) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.until[caller] = now.Add(s.window)
+	if len(s.until) < s.sweepAt {
+		return
+	}
+	for caller, until := range s.until {
+		if now.After(until) {
+			delete(s.until, caller)
+		}
+	}
+	s.sweepAt = max(2*len(s.until), minStickySweep)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStickiness(t *testing.T) {
+	s := NewStickiness(time.Minute)
+
+	assert.False(t, s.sticky("ip:10.0.0.1"))
+	s.wrote("ip:10.0.0.1")
+	assert.True(t, s.sticky("ip:10.0.0.1"), "a caller that wrote stays on the primary")
+	assert.False(t, s.sticky("ip:10.0.0.2"), "other callers do not")
+
+	s.until["ip:10.0.0.1"] = time.Now().Add(-time.Second)
+	assert.False(t, s.sticky("ip:10.0.0.1"), "until the window is over")
+	assert.NotContains(t, s.until, "ip:10.0.0.1")
+}
+
+func TestStickiness_SweepsExpiredCallers(t *testing.T) {
+	s := NewStickiness(time.Minute)
+
+	for i := range minStickySweep - 1 {
+		s.until[fmt.Sprintf("ip:%d", i)] = time.Now().Add(-time.Second)
+	}
+	s.wrote("ip:active")
+
+	assert.Equal(t, map[string]time.Time{"ip:active": s.until["ip:active"]}, s.until)
+	assert.Equal(t, minStickySweep, s.sweepAt)
+}
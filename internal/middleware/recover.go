@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panicking handler into a 500 problem response. The panic
+// value and stack are only logged.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			logging.FromContext(r.Context()).Error("Handler panic",
+				"panic", fmt.Sprint(p), "method", r.Method, "path", r.URL.Path, "stack", string(debug.Stack()))
+			problem.Write(w, r, problem.New(http.StatusInternalServerError, problem.CodeInternal, ""))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"chats/internal/problem"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover_WritesProblemWithRequestID(t *testing.T) {
+	h := RequestID(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("pq: connection reset by peer")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chats/1", nil)
+	req.Header.Set("X-Request-Id", "req-123")
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+	assert.Equal(t, "req-123", rr.Header().Get("X-Request-Id"))
+	assert.NotContains(t, rr.Body.String(), "pq:")
+
+	var response problem.Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, problem.CodeInternal, response.Code)
+	assert.Equal(t, "req-123", response.RequestID)
+	assert.Equal(t, "/api/chats/1", response.Instance)
+}
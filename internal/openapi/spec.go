@@ -0,0 +1,306 @@
+// Package openapi embeds the OpenAPI document of the HTTP API, serves it and
+// validates incoming requests against it.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+//go:embed openapi.json
+var document []byte
+
+const documentURL = "openapi.json"
+
+var methods = []string{
+	http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+	http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace,
+}
+
+// Spec is the parsed document with the request schemas compiled.
+type Spec struct {
+	operations []*operation
+	// aliases map path prefixes onto documented ones, from x-path-aliases.
+	aliases map[string]string
+}
+
+type operation struct {
+	method       string
+	path         string
+	segments     []string
+	literals     int
+	params       []parameter
+	body         *jsonschema.Schema
+	bodyRequired bool
+}
+
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	schema   *jsonschema.Schema
+}
+
+type rawParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+}
+
+type rawComponents struct {
+	Parameters    map[string]rawParameter   `json:"parameters"`
+	RequestBodies map[string]rawRequestBody `json:"requestBodies"`
+}
+
+type rawOperation struct {
+	Parameters  []rawParameter  `json:"parameters"`
+	RequestBody *rawRequestBody `json:"requestBody"`
+}
+
+type rawRequestBody struct {
+	Ref      string                     `json:"$ref"`
+	Required bool                       `json:"required"`
+	Content  map[string]json.RawMessage `json:"content"`
+}
+
+var (
+	defaultOnce sync.Once
+	defaultSpec *Spec
+	defaultErr  error
+)
+
+// Default returns the embedded document. It panics if the document does not
+// compile, which the package tests catch.
+func Default() *Spec {
+	defaultOnce.Do(func() {
+		defaultSpec, defaultErr = Load(document)
+	})
+	if defaultErr != nil {
+		panic(defaultErr)
+	}
+	return defaultSpec
+}
+
+// Document returns the raw JSON document.
+func Document() []byte {
+	return document
+}
+
+func Load(data []byte) (*Spec, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	if err := compiler.AddResource(documentURL, doc); err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Aliases    map[string]string                     `json:"x-path-aliases"`
+		Components rawComponents                         `json:"components"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+
+	spec := &Spec{aliases: raw.Aliases}
+	for path, item := range raw.Paths {
+		var shared []rawParameter
+		if params, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(params, &shared); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		for _, method := range methods {
+			body, ok := item[strings.ToLower(method)]
+			if !ok {
+				continue
+			}
+
+			var rawOp rawOperation
+			if err := json.Unmarshal(body, &rawOp); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+
+			op, err := compileOperation(compiler, raw.Components, path, method, shared, rawOp)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			spec.operations = append(spec.operations, op)
+		}
+	}
+
+	// Literal segments win over templated ones, e.g. /a/new before /a/{id}.
+	sort.SliceStable(spec.operations, func(i, j int) bool {
+		return spec.operations[i].literals > spec.operations[j].literals
+	})
+
+	return spec, nil
+}
+
+func compileOperation(compiler *jsonschema.Compiler, components rawComponents, path, method string, shared []rawParameter, rawOp rawOperation) (*operation, error) {
+	op := &operation{method: method, path: path, segments: splitPath(path)}
+	for _, segment := range op.segments {
+		if !isTemplate(segment) {
+			op.literals++
+		}
+	}
+
+	base := "#/paths/" + escape(path)
+	lists := []struct {
+		params []rawParameter
+		ptr    string
+	}{
+		{shared, base + "/parameters"},
+		{rawOp.Parameters, base + "/" + strings.ToLower(method) + "/parameters"},
+	}
+
+	for _, list := range lists {
+		for i, p := range list.params {
+			ptr := fmt.Sprintf("%s/%d", list.ptr, i)
+			if p.Ref != "" {
+				name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+				if !ok {
+					return nil, fmt.Errorf("unsupported parameter reference %q", p.Ref)
+				}
+				if p, ok = components.Parameters[name]; !ok {
+					return nil, fmt.Errorf("unknown parameter %q", name)
+				}
+				ptr = "#/components/parameters/" + escape(name)
+			}
+
+			schema, err := compiler.Compile(documentURL + ptr + "/schema")
+			if err != nil {
+				return nil, err
+			}
+			op.params = append(op.params, parameter{
+				name:     p.Name,
+				in:       p.In,
+				required: p.Required || p.In == "path",
+				schema:   schema,
+			})
+		}
+	}
+
+	if body := rawOp.RequestBody; body != nil {
+		ptr := base + "/" + strings.ToLower(method) + "/requestBody"
+		if body.Ref != "" {
+			name, ok := strings.CutPrefix(body.Ref, "#/components/requestBodies/")
+			if !ok {
+				return nil, fmt.Errorf("unsupported request body reference %q", body.Ref)
+			}
+			resolved, ok := components.RequestBodies[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown request body %q", name)
+			}
+			body, ptr = &resolved, "#/components/requestBodies/"+escape(name)
+		}
+
+		op.bodyRequired = body.Required
+		if content, ok := body.Content["application/json"]; ok {
+			var media struct {
+				Schema json.RawMessage `json:"schema"`
+			}
+			if err := json.Unmarshal(content, &media); err != nil {
+				return nil, err
+			}
+			if media.Schema != nil {
+				schema, err := compiler.Compile(documentURL + ptr + "/content/application~1json/schema")
+				if err != nil {
+					return nil, err
+				}
+				op.body = schema
+			}
+		}
+	}
+
+	return op, nil
+}
+
+// Has reports whether the document describes method on the given path
+// template, e.g. "/api/chats/{id}".
+func (s *Spec) Has(method, path string) bool {
+	path = s.resolveAlias(path)
+	for _, op := range s.operations {
+		if op.method == method && op.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP serves the raw document.
+func (s *Spec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(document)
+}
+
+func (s *Spec) find(method, path string) (*operation, map[string]string) {
+	segments := splitPath(s.resolveAlias(path))
+	for _, op := range s.operations {
+		if op.method != method || len(op.segments) != len(segments) {
+			continue
+		}
+		if values, ok := op.match(segments); ok {
+			return op, values
+		}
+	}
+	return nil, nil
+}
+
+func (s *Spec) resolveAlias(path string) string {
+	for alias, target := range s.aliases {
+		if rest, ok := strings.CutPrefix(path, alias); ok {
+			return target + rest
+		}
+	}
+	return path
+}
+
+func (op *operation) match(segments []string) (map[string]string, bool) {
+	values := make(map[string]string)
+	for i, segment := range op.segments {
+		if isTemplate(segment) {
+			if segments[i] == "" {
+				return nil, false
+			}
+			values[segment[1:len(segment)-1]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func isTemplate(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// escape encodes a JSON pointer token.
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault_Compiles(t *testing.T) {
+	spec, err := Load(document)
+	require.NoError(t, err)
+	assert.True(t, spec.Has(http.MethodPost, "/api/chats"))
+	assert.True(t, spec.Has(http.MethodGet, "/api/chats/{id}/messages"))
+	assert.True(t, spec.Has(http.MethodGet, "/api/v1/chats/{id}/messages"))
+	assert.True(t, spec.Has(http.MethodGet, "/api/v2/chats/{id}/messages"))
+}
+
+func TestValidate(t *testing.T) {
+	passed := false
+	h := Default().Validate(1 << 20)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passed = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		header   map[string]string
+		wantPass bool
+		wantBody []string
+	}{
+		{name: "valid chat", method: http.MethodPost, target: "/api/chats", body: `{"title":"general"}`, wantPass: true},
+		{name: "missing title", method: http.MethodPost, target: "/api/chats", body: `{}`, wantBody: []string{`"field":"body.title"`, `"message":"is required"`}},
+		{name: "wrong type", method: http.MethodPost, target: "/api/chats", body: `{"title":42}`, wantBody: []string{`"field":"body.title"`}},
+		{name: "invalid JSON", method: http.MethodPost, target: "/api/chats", body: `{`, wantBody: []string{`"field":"body"`}},
+		{name: "empty body", method: http.MethodPost, target: "/api/chats/1/messages", wantBody: []string{`"field":"body"`}},
+		{name: "long text is left to the limits", method: http.MethodPost, target: "/api/chats/1/messages", body: `{"text":"` + strings.Repeat("a", 5001) + `"}`, wantPass: true},
+		{name: "bad path ID", method: http.MethodGet, target: "/api/chats/abc", wantBody: []string{`"field":"path.id"`}},
+		{name: "zero path ID", method: http.MethodDelete, target: "/api/chats/0", wantBody: []string{`"field":"path.id"`}},
+		{name: "bad query", method: http.MethodGet, target: "/api/chats/1/messages?after_seq=-1", wantBody: []string{`"field":"query.after_seq"`}},
+		{name: "valid query", method: http.MethodGet, target: "/api/chats/1/messages?after_seq=5&limit=10", wantPass: true},
+		{name: "bad enum in array", method: http.MethodPost, target: "/api/admin/webhooks", body: `{"url":"https://example.com","event_types":["nope"]}`, wantBody: []string{`"field":"body.event_types.0"`}},
+		{name: "numeric idempotency key", method: http.MethodPost, target: "/api/chats", body: `{"title":"x"}`, header: map[string]string{"Idempotency-Key": "123"}, wantPass: true},
+		{name: "v1 alias", method: http.MethodPost, target: "/api/v1/chats", body: `{}`, wantBody: []string{`"field":"body.title"`}},
+		{name: "v2 body", method: http.MethodPost, target: "/api/v2/chats/1/messages", body: `{"text":""}`, wantBody: []string{`"field":"body.text"`}},
+		{name: "unknown path", method: http.MethodGet, target: "/api/unknown", wantPass: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passed = false
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantPass, passed, rr.Body.String())
+			if !tt.wantPass {
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+				for _, want := range tt.wantBody {
+					assert.Contains(t, rr.Body.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_BodyTooLarge(t *testing.T) {
+	passed := false
+	h := Default().Validate(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passed = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chats", strings.NewReader(`{"title":"`+strings.Repeat("a", 64)+`"}`))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `"code":"payload_too_large"`)
+}
@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"bytes"
+	"chats/internal/codec"
+	"chats/internal/logging"
+	"chats/internal/problem"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var printer = message.NewPrinter(language.English)
+
+// Validate rejects requests that do not match the document with a 400
+// validation problem listing the offending fields, and JSON bodies over
+// maxBody bytes with 413; zero leaves them uncapped. Requests to paths the
+// document does not describe are passed through for the router to answer.
+func (s *Spec) Validate(maxBody int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, pathValues := s.find(r.Method, r.URL.Path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fields, err := op.validate(w, r, pathValues, maxBody)
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				problem.Write(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge,
+					fmt.Sprintf("The request body must not exceed %d bytes.", tooLarge.Limit)))
+				return
+			case err != nil:
+				logging.FromContext(r.Context()).Warn("Error reading request body", "error", err)
+				problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, "The request body could not be read."))
+				return
+			}
+			if len(fields) > 0 {
+				logging.FromContext(r.Context()).Warn("Request validation failed", "method", r.Method, "path", r.URL.Path, "fields", fields)
+				problem.Write(w, r, problem.Validation(fields))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (op *operation) validate(w http.ResponseWriter, r *http.Request, pathValues map[string]string, maxBody int64) ([]problem.FieldError, error) {
+	var fields []problem.FieldError
+
+	for _, p := range op.params {
+		var raw string
+		var present bool
+		switch p.in {
+		case "path":
+			raw, present = pathValues[p.name]
+		case "query":
+			present = r.URL.Query().Has(p.name)
+			raw = r.URL.Query().Get(p.name)
+		case "header":
+			raw = r.Header.Get(p.name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		field := p.in + "." + p.name
+		if !present {
+			if p.required {
+				fields = append(fields, problem.FieldError{Field: field, Message: "is required"})
+			}
+			continue
+		}
+
+		if err := p.schema.Validate(coerce(raw, p.schema)); err != nil {
+			fields = append(fields, fieldErrors(field, err)...)
+		}
+	}
+
+	// Only JSON bodies are checked against the schema; handlers validate
+	// what they decode from the other formats.
+	if c, ok := codec.ForContentType(r.Header.Get("Content-Type")); op.body == nil || !ok || c != codec.JSON {
+		return fields, nil
+	}
+
+	reader := r.Body
+	if maxBody > 0 {
+		reader = http.MaxBytesReader(w, reader, maxBody)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if op.bodyRequired {
+			fields = append(fields, problem.FieldError{Field: "body", Message: "is required"})
+		}
+		return fields, nil
+	}
+
+	body, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return append(fields, problem.FieldError{Field: "body", Message: "is not valid JSON"}), nil
+	}
+	if err := op.body.Validate(body); err != nil {
+		fields = append(fields, fieldErrors("body", err)...)
+	}
+
+	return fields, nil
+}
+
+// coerce converts a raw parameter to the JSON type its schema expects so
+// "limit=10" validates against {"type": "integer"}.
+func coerce(raw string, schema *jsonschema.Schema) any {
+	for s := schema; s != nil; s = s.Ref {
+		if s.Types == nil || s.Types.IsEmpty() {
+			continue
+		}
+		for _, t := range s.Types.ToStrings() {
+			switch t {
+			case "integer", "number":
+				if v, err := jsonschema.UnmarshalJSON(strings.NewReader(raw)); err == nil {
+					if n, ok := v.(json.Number); ok {
+						return n
+					}
+				}
+			case "boolean":
+				if raw == "true" || raw == "false" {
+					return raw == "true"
+				}
+			}
+		}
+		break
+	}
+	return raw
+}
+
+func fieldErrors(prefix string, err error) []problem.FieldError {
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return []problem.FieldError{{Field: prefix, Message: err.Error()}}
+	}
+
+	var fields []problem.FieldError
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+			return
+		}
+
+		location := strings.Join(append([]string{prefix}, e.InstanceLocation...), ".")
+		if required, ok := e.ErrorKind.(*kind.Required); ok {
+			for _, name := range required.Missing {
+				fields = append(fields, problem.FieldError{Field: location + "." + name, Message: "is required"})
+			}
+			return
+		}
+		fields = append(fields, problem.FieldError{Field: location, Message: e.ErrorKind.LocalizedString(printer)})
+	}
+	walk(verr)
+
+	return fields
+}
@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"chats/internal/config"
+	"chats/internal/domain"
+	"chats/internal/repositories"
+	"chats/internal/services"
+	"context"
+	"log/slog"
+	"time"
+)
+
+const maxRetryDelay = 5 * time.Minute
+
+// Relay moves committed outbox entries to the publisher. An entry is marked
+// processed only after a successful publish in the same transaction that
+// locked it, so every event is delivered at least once.
+type Relay struct {
+	repo      repositories.OutboxRepository
+	txManager repositories.TxManager
+	publisher services.EventPublisher
+	cfg       config.OutboxConfig
+}
+
+func NewRelay(repo repositories.OutboxRepository, txManager repositories.TxManager, publisher services.EventPublisher, cfg config.OutboxConfig) *Relay {
+	return &Relay{
+		repo:      repo,
+		txManager: txManager,
+		publisher: publisher,
+		cfg:       cfg,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	logger := slog.Default()
+
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(r.cfg.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			for {
+				n, err := r.RelayBatch(ctx)
+				if err != nil {
+					logger.Error("Error relaying outbox", "error", err)
+				}
+				if err != nil || n < r.cfg.BatchSize {
+					break
+				}
+			}
+		case <-cleanup.C:
+			deleted, err := r.repo.DeleteProcessedBefore(ctx, time.Now().Add(-r.cfg.Retention))
+			if err != nil {
+				logger.Error("Error cleaning up outbox", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Info("Outbox cleaned up", "deleted", deleted)
+			}
+		}
+	}
+}
+
+// RelayBatch publishes one batch of due entries and returns how many were claimed.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	var claimed int
+
+	err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		entries, err := r.repo.FetchPending(ctx, r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		claimed = len(entries)
+
+		processed := make([]uint64, 0, len(entries))
+		for _, entry := range entries {
+			if err := r.publish(ctx, entry); err != nil {
+				slog.Default().Warn("Outbox publish failed",
+					"event_id", entry.EventID, "attempts", entry.Attempts+1, "error", err)
+
+				retryAt := time.Now().Add(retryDelay(entry.Attempts+1, r.cfg.PollInterval))
+				if err := r.repo.MarkFailed(ctx, entry.ID, err, retryAt); err != nil {
+					return err
+				}
+				continue
+			}
+			processed = append(processed, entry.ID)
+		}
+
+		return r.repo.MarkProcessed(ctx, processed)
+	})
+
+	return claimed, err
+}
+
+func (r *Relay) publish(ctx context.Context, entry domain.OutboxEntry) error {
+	event, err := entry.Event()
+	if err != nil {
+		return err
+	}
+	return r.publisher.Publish(ctx, event)
+}
+
+func retryDelay(attempts int, base time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
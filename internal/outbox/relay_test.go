@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"chats/internal/config"
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTxManager struct {
+	committed int
+}
+
+func (f *fakeTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	f.committed++
+	return nil
+}
+
+type fakeOutboxRepository struct {
+	entries   []domain.OutboxEntry
+	processed []uint64
+	failed    map[uint64]error
+}
+
+func (f *fakeOutboxRepository) Add(context.Context, domain.Event) error { return nil }
+
+func (f *fakeOutboxRepository) FetchPending(_ context.Context, limit int) ([]domain.OutboxEntry, error) {
+	return f.entries[:min(limit, len(f.entries))], nil
+}
+
+func (f *fakeOutboxRepository) MarkProcessed(_ context.Context, ids []uint64) error {
+	f.processed = append(f.processed, ids...)
+	return nil
+}
+
+func (f *fakeOutboxRepository) MarkFailed(_ context.Context, id uint64, cause error, _ time.Time) error {
+	f.failed[id] = cause
+	return nil
+}
+
+func (f *fakeOutboxRepository) DeleteProcessedBefore(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+type publisherFunc func(ctx context.Context, event domain.Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, event domain.Event) error { return f(ctx, event) }
+
+func entryFor(t *testing.T, id uint64, eventType domain.EventType) domain.OutboxEntry {
+	event, err := domain.NewEvent(eventType, 1, map[string]uint64{"id": id})
+	require.NoError(t, err)
+	entry, err := domain.NewOutboxEntry(event)
+	require.NoError(t, err)
+	entry.ID = id
+	return *entry
+}
+
+func TestRelay_RelayBatch_MarksOnlyPublishedEntries(t *testing.T) {
+	repo := &fakeOutboxRepository{
+		entries: []domain.OutboxEntry{
+			entryFor(t, 1, domain.EventChatCreated),
+			entryFor(t, 2, domain.EventMessageCreated),
+			entryFor(t, 3, domain.EventMessageCreated),
+		},
+		failed: map[uint64]error{},
+	}
+	tx := &fakeTxManager{}
+
+	var published []domain.EventType
+	publisher := publisherFunc(func(_ context.Context, event domain.Event) error {
+		if event.Data != nil && string(event.Data) == `{"id":2}` {
+			return errors.New("receiver down")
+		}
+		published = append(published, event.Type)
+		return nil
+	})
+
+	relay := NewRelay(repo, tx, publisher, config.OutboxConfig{BatchSize: 10, PollInterval: time.Second})
+
+	n, err := relay.RelayBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 1, tx.committed)
+	assert.Equal(t, []uint64{1, 3}, repo.processed)
+	assert.Contains(t, repo.failed, uint64(2))
+	assert.Equal(t, []domain.EventType{domain.EventChatCreated, domain.EventMessageCreated}, published)
+}
+
+func TestRelay_RelayBatch_RespectsBatchSize(t *testing.T) {
+	repo := &fakeOutboxRepository{
+		entries: []domain.OutboxEntry{entryFor(t, 1, domain.EventChatCreated), entryFor(t, 2, domain.EventChatCreated)},
+		failed:  map[uint64]error{},
+	}
+	publisher := publisherFunc(func(context.Context, domain.Event) error { return nil })
+
+	relay := NewRelay(repo, &fakeTxManager{}, publisher, config.OutboxConfig{BatchSize: 1, PollInterval: time.Second})
+
+	n, err := relay.RelayBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []uint64{1}, repo.processed)
+}
+
+func TestRetryDelay_Caps(t *testing.T) {
+	assert.Equal(t, time.Second, retryDelay(1, time.Second))
+	assert.Equal(t, 4*time.Second, retryDelay(3, time.Second))
+	assert.Equal(t, maxRetryDelay, retryDelay(50, time.Second))
+}
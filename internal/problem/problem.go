@@ -0,0 +1,166 @@
+// Package problem writes API errors as RFC 7807 application/problem+json.
+package problem
+
+import (
+	"chats/internal/domain"
+	"chats/internal/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+const ContentType = "application/problem+json"
+
+// Stable machine-readable codes. The problem type is derived from the code
+// so clients can switch on either.
+const (
+	CodeBadRequest               = "bad_request"
+	CodeValidationFailed         = "validation_failed"
+	CodeInvalidInput             = "invalid_input"
+	CodeNotFound                 = "not_found"
+	CodeAlreadyExists            = "already_exists"
+	CodePreconditionFailed       = "precondition_failed"
+	CodePreconditionRequired     = "precondition_required"
+	CodeBatchAborted             = "batch_aborted"
+	CodeMethodNotAllowed         = "method_not_allowed"
+	CodeNotAcceptable            = "not_acceptable"
+	CodeUnsupportedMediaType     = "unsupported_media_type"
+	CodeUnauthorized             = "unauthorized"
+	CodeForbidden                = "forbidden"
+	CodePayloadTooLarge          = "payload_too_large"
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	CodeRequestCancelled         = "request_cancelled"
+	CodeTimeout                  = "timeout"
+	CodeUnavailable              = "unavailable"
+	CodeInternal                 = "internal"
+)
+
+const (
+	typePrefix = "urn:chats:problem:"
+
+	// statusClientClosedRequest is nginx's non-standard code for requests the
+	// client gave up on; nobody reads the response, it only shows up in logs.
+	statusClientClosedRequest = 499
+)
+
+// FieldError points at a single invalid part of the request, e.g.
+// "body.title", "query.limit" or "path.id".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Validation reports invalid request fields.
+func Validation(fields []FieldError) *Problem {
+	p := New(http.StatusBadRequest, CodeValidationFailed, "The request does not match the API schema.")
+	p.Errors = fields
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Code + ": " + p.Detail
+	}
+	return p.Code
+}
+
+// From maps err to a problem. Domain errors carry messages written for
+// clients; anything else becomes an opaque 500.
+func From(err error) *Problem {
+	var p *Problem
+	switch {
+	case errors.As(err, &p):
+		return p
+	case errors.Is(err, domain.ErrNotFound):
+		return New(http.StatusNotFound, CodeNotFound, domain.Describe(err, domain.ErrNotFound))
+	case errors.Is(err, domain.ErrInvalidInput):
+		return New(http.StatusBadRequest, CodeInvalidInput, domain.Describe(err, domain.ErrInvalidInput))
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return New(http.StatusConflict, CodeAlreadyExists, domain.Describe(err, domain.ErrAlreadyExists))
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return New(http.StatusPreconditionFailed, CodePreconditionFailed, "The resource was changed since the version in If-Match.")
+	case errors.Is(err, domain.ErrUnavailable):
+		return New(http.StatusServiceUnavailable, CodeUnavailable, "The service is temporarily unavailable, retry later.")
+	case errors.Is(err, domain.ErrTimeout):
+		return New(http.StatusGatewayTimeout, CodeTimeout, "The database did not respond in time, retry later.")
+	case errors.Is(err, context.Canceled):
+		return New(statusClientClosedRequest, CodeRequestCancelled, "")
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(http.StatusGatewayTimeout, CodeTimeout, "")
+	default:
+		return New(http.StatusInternalServerError, CodeInternal, "")
+	}
+}
+
+// Error writes err as a problem. Errors that map to a 5xx are logged with
+// the request ID and never exposed to the client.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, Resolve(r, err))
+}
+
+// Resolve is From for errors reported inside a response rather than as it,
+// such as the items of a batch: 5xx are logged the same way as by Error.
+func Resolve(r *http.Request, err error) *Problem {
+	p := From(err)
+	if p.Status >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error("Internal error",
+			"error", err, "method", r.Method, "path", r.URL.Path)
+	}
+	return p
+}
+
+// Write writes p, filling in the request ID and instance.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	response := Complete(r, p)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(response.Status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.FromContext(r.Context()).Error("Error encoding problem", "error", err)
+	}
+}
+
+// Complete returns a copy of p with the request ID and instance filled in.
+func Complete(r *http.Request, p *Problem) Problem {
+	response := *p
+	response.RequestID = chimw.GetReqID(r.Context())
+	if response.Instance == "" {
+		response.Instance = r.URL.Path
+	}
+	return response
+}
+
+// MethodNotAllowed and NotFound are router fallbacks.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, ""))
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, New(http.StatusNotFound, CodeNotFound, ""))
+}
@@ -0,0 +1,42 @@
+package problem
+
+import (
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{domain.ErrNotFound, http.StatusNotFound, CodeNotFound, "not found"},
+		{fmt.Errorf("creating webhook: %w", domain.NewError(domain.ErrInvalidInput, "unknown event type")), http.StatusBadRequest, CodeInvalidInput, "invalid input: unknown event type"},
+		{fmt.Errorf("%w: %w", domain.ErrNotFound, errors.New(`pq: relation "chats" does not exist`)), http.StatusNotFound, CodeNotFound, "not found"},
+		{fmt.Errorf("%w: title %q", domain.ErrAlreadyExists, "Общий"), http.StatusConflict, CodeAlreadyExists, "already exists"},
+		{domain.NewError(domain.ErrNotFound, "webhook 5"), http.StatusNotFound, CodeNotFound, "not found: webhook 5"},
+		{domain.ErrAlreadyExists, http.StatusConflict, CodeAlreadyExists, "already exists"},
+		{fmt.Errorf("update chat: %w", domain.ErrVersionMismatch), http.StatusPreconditionFailed, CodePreconditionFailed, "The resource was changed since the version in If-Match."},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout, ""},
+		{fmt.Errorf("%w: %w", domain.ErrTimeout, context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout, "The database did not respond in time, retry later."},
+		{fmt.Errorf("%w: too many clients", domain.ErrUnavailable), http.StatusServiceUnavailable, CodeUnavailable, "The service is temporarily unavailable, retry later."},
+		{errors.New(`pq: relation "chats" does not exist`), http.StatusInternalServerError, CodeInternal, ""},
+		{New(http.StatusUnauthorized, CodeUnauthorized, "nope"), http.StatusUnauthorized, CodeUnauthorized, "nope"},
+	}
+
+	for _, tt := range tests {
+		p := From(tt.err)
+		assert.Equal(t, tt.wantStatus, p.Status, tt.err.Error())
+		assert.Equal(t, tt.wantCode, p.Code, tt.err.Error())
+		assert.Equal(t, "urn:chats:problem:"+tt.wantCode, p.Type, tt.err.Error())
+		assert.Equal(t, tt.wantDetail, p.Detail, tt.err.Error())
+	}
+}
@@ -0,0 +1,46 @@
+// Package protoconv converts domain types to the chats.v1 protobuf
+// messages shared by the gRPC API and protobuf responses over HTTP.
+package protoconv
+
+import (
+	chatsv1 "chats/api/chats/v1"
+	"chats/internal/domain"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Chat(chat *domain.Chat) *chatsv1.Chat {
+	return &chatsv1.Chat{
+		Id:        uint64(chat.ID),
+		Title:     chat.Title,
+		LastSeq:   chat.LastSeq,
+		CreatedAt: timestamppb.New(chat.CreatedAt),
+		UpdatedAt: timestamppb.New(chat.UpdatedAt),
+		Version:   chat.Version,
+		Messages:  Messages(chat.Message),
+	}
+}
+
+func Message(message *domain.Message) *chatsv1.Message {
+	result := &chatsv1.Message{
+		Id:        uint64(message.ID),
+		ChatId:    uint64(message.ChatID),
+		Seq:       message.Seq,
+		Text:      message.Text,
+		CreatedAt: timestamppb.New(message.CreatedAt),
+		Author:    message.Author,
+		Version:   message.Version,
+	}
+	if message.EditedAt != nil {
+		result.EditedAt = timestamppb.New(*message.EditedAt)
+	}
+	return result
+}
+
+func Messages(messages []domain.Message) []*chatsv1.Message {
+	result := make([]*chatsv1.Message, 0, len(messages))
+	for i := range messages {
+		result = append(result, Message(&messages[i]))
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package realtime
+
+import (
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const pageSize = 100
+
+// ErrClosed ends streams when the hub shuts down; subscribers should
+// resume from their last seq, e.g. on another replica.
+var ErrClosed = errors.New("realtime: hub closed")
+
+type MessageLister interface {
+	ListMessages(ctx context.Context, chatID uint, cursor domain.MessageCursor) ([]domain.Message, error)
+}
+
+// Hub wakes up chat subscribers when the outbox relays a message event.
+// Notifications carry no data: subscribers always read from the database by
+// seq, so duplicated or missed notifications cannot reorder or drop messages.
+// The poll interval covers messages relayed by other replicas.
+type Hub struct {
+	messages     MessageLister
+	pollInterval time.Duration
+
+	mu   sync.Mutex
+	subs map[uint]map[chan struct{}]struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func NewHub(messages MessageLister, pollInterval time.Duration) *Hub {
+	return &Hub{
+		messages:     messages,
+		pollInterval: pollInterval,
+		subs:         make(map[uint]map[chan struct{}]struct{}),
+		closed:       make(chan struct{}),
+	}
+}
+
+// Close ends all running and future streams with ErrClosed, so servers
+// waiting for them can shut down.
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() { close(h.closed) })
+}
+
+// Publish is an events.Handler.
+func (h *Hub) Publish(_ context.Context, event domain.Event) error {
+	if event.Type != domain.EventMessageCreated && event.Type != domain.EventChatDeleted {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[event.ChatID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (h *Hub) subscribe(chatID uint) (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subs[chatID] == nil {
+		h.subs[chatID] = make(map[chan struct{}]struct{})
+	}
+	h.subs[chatID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[chatID], ch)
+		if len(h.subs[chatID]) == 0 {
+			delete(h.subs, chatID)
+		}
+	}
+}
+
+// Stream calls send for every message of the chat with seq > afterSeq, in seq
+// order, until ctx is done, send fails, the chat disappears or the hub is
+// closed.
+func (h *Hub) Stream(ctx context.Context, chatID uint, afterSeq uint64, send func(domain.Message) error) error {
+	select {
+	case <-h.closed:
+		return ErrClosed
+	default:
+	}
+
+	wake, unsubscribe := h.subscribe(chatID)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			page, err := h.messages.ListMessages(ctx, chatID, domain.MessageCursor{AfterSeq: afterSeq, Limit: pageSize})
+			if err != nil {
+				return err
+			}
+			for _, message := range page {
+				if err := send(message); err != nil {
+					return err
+				}
+				afterSeq = message.Seq
+			}
+			if len(page) < pageSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-h.closed:
+			return ErrClosed
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type chatRepository struct {
@@ -20,7 +21,7 @@ func NewChatRepository(db *gorm.DB) ChatRepository {
 
 func (c chatRepository) Create(ctx context.Context, chat *domain.Chat) error {
 	var existingChat domain.Chat
-	err := c.db.WithContext(ctx).
+	err := conn(ctx, c.db).
 		Where("title = ?", chat.Title).
 		First(&existingChat).Error
 
@@ -32,13 +33,13 @@ func (c chatRepository) Create(ctx context.Context, chat *domain.Chat) error {
 		return err
 	}
 
-	return c.db.WithContext(ctx).Create(chat).Error
+	return conn(ctx, c.db).Create(chat).Error
 }
 
 func (c chatRepository) GetByID(ctx context.Context, id uint, withMessage bool, limit int) (*domain.Chat, error) {
 	var chat domain.Chat
 
-	query := c.db.WithContext(ctx).Model(&domain.Chat{}).Where("id = ?", id)
+	query := conn(ctx, c.db).Model(&domain.Chat{}).Where("id = ?", id)
 
 	if withMessage {
 		query = query.Preload("Message", func(db *gorm.DB) *gorm.DB {
@@ -56,25 +57,90 @@ func (c chatRepository) GetByID(ctx context.Context, id uint, withMessage bool,
 	return &chat, nil
 }
 
-func (c chatRepository) Delete(ctx context.Context, id uint) error {
-	result := c.db.WithContext(ctx).Delete(&domain.Chat{}, id)
+func (c chatRepository) GetByIDs(ctx context.Context, ids []uint) ([]domain.Chat, error) {
+	var chats []domain.Chat
+
+	err := conn(ctx, c.db).Where("id IN ?", ids).Find(&chats).Error
+	return chats, err
+}
+
+// Update renames the chat, checking version in the UPDATE itself.
+func (c chatRepository) Update(ctx context.Context, chat *domain.Chat, version uint64) error {
+	var taken int64
+	err := conn(ctx, c.db).Model(&domain.Chat{}).
+		Where("title = ? AND id <> ?", chat.Title, chat.ID).
+		Count(&taken).Error
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return domain.ErrAlreadyExists
+	}
+
+	query := conn(ctx, c.db).Model(chat).Clauses(clause.Returning{})
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(map[string]any{
+		"title":      chat.Title,
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": gorm.Expr("now()"),
+	})
 
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return domain.ErrNotFound
+		return c.missing(ctx, chat.ID)
 	}
 	return nil
 }
 
+func (c chatRepository) Delete(ctx context.Context, id uint, version uint64) error {
+	query := conn(ctx, c.db).Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Delete(&domain.Chat{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return c.missing(ctx, id)
+	}
+	return nil
+}
+
+func (c chatRepository) missing(ctx context.Context, id uint) error {
+	exists, err := c.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return domain.ErrVersionMismatch
+	}
+	return domain.ErrNotFound
+}
+
 func (c chatRepository) Exists(ctx context.Context, id uint) (bool, error) {
 	var count int64
 
-	err := c.db.WithContext(ctx).Model(&domain.Chat{}).
+	err := conn(ctx, c.db).Model(&domain.Chat{}).
 		Where("id = ?", id).
 		Count(&count).
 		Error
 
 	return count > 0, err
 }
+
+func (c chatRepository) List(ctx context.Context, afterID uint, limit int) ([]domain.Chat, error) {
+	var chats []domain.Chat
+
+	err := conn(ctx, c.db).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&chats).Error
+	return chats, err
+}
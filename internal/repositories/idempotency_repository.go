@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"chats/internal/domain"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+// Claim inserts the record unless a live one exists for the same scope and
+// key; expired records and abandoned claims are taken over. When the key is
+// already held, the stored record is returned with claimed=false.
+func (i idempotencyRepository) Claim(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, bool, error) {
+	result := conn(ctx, i.db).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"request_hash":     record.RequestHash,
+			"status_code":      0,
+			"response_headers": domain.Header{},
+			"content_type":     "",
+			"response_body":    nil,
+			"created_at":       gorm.Expr("NOW()"),
+			"locked_until":     record.LockedUntil,
+			"expires_at":       record.ExpiresAt,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "idempotency_keys.expires_at <= NOW() OR (idempotency_keys.status_code = 0 AND idempotency_keys.locked_until <= NOW())"},
+		}},
+	}).Create(record)
+
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing domain.IdempotencyRecord
+	err := conn(ctx, i.db).
+		Where("scope = ? AND key = ?", record.Scope, record.Key).
+		First(&existing).Error
+	if err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+func (i idempotencyRepository) Complete(ctx context.Context, record *domain.IdempotencyRecord) error {
+	return claimedBy(conn(ctx, i.db), record).Model(&domain.IdempotencyRecord{}).
+		Updates(map[string]any{
+			"status_code":      record.StatusCode,
+			"response_headers": record.ResponseHeaders,
+			"response_body":    record.ResponseBody,
+		}).Error
+}
+
+func (i idempotencyRepository) Release(ctx context.Context, record *domain.IdempotencyRecord) error {
+	return claimedBy(conn(ctx, i.db), record).Delete(&domain.IdempotencyRecord{}).Error
+}
+
+// claimedBy matches the record's claim unless another request took it over.
+func claimedBy(query *gorm.DB, record *domain.IdempotencyRecord) *gorm.DB {
+	return query.Where("scope = ? AND key = ? AND status_code = 0 AND locked_until = ?",
+		record.Scope, record.Key, record.LockedUntil)
+}
+
+func (i idempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := conn(ctx, i.db).
+		Where("expires_at <= NOW()").
+		Delete(&domain.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
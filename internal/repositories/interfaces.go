@@ -3,16 +3,78 @@ package repositories
 import (
 	"chats/internal/domain"
 	"context"
+	"iter"
+	"time"
 )
 
 type ChatRepository interface {
 	Create(ctx context.Context, chat *domain.Chat) error
 	GetByID(ctx context.Context, id uint, withMessage bool, limit int) (*domain.Chat, error)
-	Delete(ctx context.Context, id uint) error
+	GetByIDs(ctx context.Context, ids []uint) ([]domain.Chat, error)
+	// Update and Delete apply only while the chat is at version; zero
+	// matches any version.
+	Update(ctx context.Context, chat *domain.Chat, version uint64) error
+	Delete(ctx context.Context, id uint, version uint64) error
 	Exists(ctx context.Context, id uint) (bool, error)
+	// List returns up to limit chats with IDs above afterID, by ID.
+	List(ctx context.Context, afterID uint, limit int) ([]domain.Chat, error)
 }
 
 type MessageRepository interface {
 	Create(ctx context.Context, message *domain.Message) error
-	GetByChatID(ctx context.Context, chatID uint, limit int) ([]domain.Message, error)
+	CreateBatch(ctx context.Context, chatID uint, messages []domain.Message) error
+	// Update applies only while the message is at version; zero matches any version.
+	Update(ctx context.Context, message *domain.Message, version uint64) error
+	Delete(ctx context.Context, chatID uint, seq uint64, version uint64) error
+	GetByChatID(ctx context.Context, chatID uint, cursor domain.MessageCursor) ([]domain.Message, error)
+	GetLatestByChatIDs(ctx context.Context, chatIDs []uint, limit int) ([]domain.Message, error)
+	IterateByChatID(ctx context.Context, chatID uint, filter domain.HistoryFilter) iter.Seq2[domain.Message, error]
+	// CountCreatedBefore and DeleteCreatedBefore select the messages
+	// created before the given time, in one chat or in all chats when
+	// chatID is zero.
+	CountCreatedBefore(ctx context.Context, chatID uint, before time.Time) (int64, error)
+	DeleteCreatedBefore(ctx context.Context, chatID uint, before time.Time) (int64, error)
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *domain.Webhook) error
+	GetByID(ctx context.Context, id uint) (*domain.Webhook, error)
+	List(ctx context.Context) ([]domain.Webhook, error)
+	Delete(ctx context.Context, id uint) error
+	SetActive(ctx context.Context, id uint, active bool) error
+	FindSubscribed(ctx context.Context, chatID uint, eventType domain.EventType) ([]domain.Webhook, error)
+	RecordSuccess(ctx context.Context, id uint) error
+	RecordFailure(ctx context.Context, id uint, disableAfter int) (disabled bool, err error)
+
+	CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]domain.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, deliveryID uint) (*domain.WebhookDelivery, error)
+
+	CreateJob(ctx context.Context, job *domain.WebhookJob) error
+	// ClaimJobs hides up to limit due jobs from other claims for lease; a
+	// job that is neither deleted nor rescheduled by then is claimed again.
+	ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookJob, error)
+	RescheduleJob(ctx context.Context, id uint64, attempt int, at time.Time) error
+	DeleteJob(ctx context.Context, id uint64) error
+}
+
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type OutboxRepository interface {
+	Add(ctx context.Context, event domain.Event) error
+	FetchPending(ctx context.Context, limit int) ([]domain.OutboxEntry, error)
+	MarkProcessed(ctx context.Context, ids []uint64) error
+	MarkFailed(ctx context.Context, id uint64, cause error, retryAt time.Time) error
+	DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type IdempotencyRepository interface {
+	Claim(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, bool, error)
+	// Complete stores the response and Release drops the claim, both only
+	// while the claim is still the record's.
+	Complete(ctx context.Context, record *domain.IdempotencyRecord) error
+	Release(ctx context.Context, record *domain.IdempotencyRecord) error
+	DeleteExpired(ctx context.Context) (int64, error)
 }
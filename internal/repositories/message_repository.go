@@ -3,10 +3,21 @@ package repositories
 import (
 	"chats/internal/domain"
 	"context"
+	"iter"
+	"slices"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// importInsertBatch keeps a multi-row insert well below the 65535 bind
+// parameters postgres accepts per statement.
+const importInsertBatch = 1000
+
+// iteratePageSize is how many messages IterateByChatID holds at a time.
+const iteratePageSize = 500
+
 type messageRepository struct {
 	db *gorm.DB
 }
@@ -17,17 +28,239 @@ func NewMessageRepository(db *gorm.DB) MessageRepository {
 	}
 }
 
+// Create assigns the next seq of the chat. Incrementing chats.last_seq locks
+// the chat row until commit, so concurrent inserts are serialized per chat and
+// a rolled-back insert gives its seq back: the sequence has no gaps.
 func (m messageRepository) Create(ctx context.Context, message *domain.Message) error {
-	return m.db.WithContext(ctx).Create(message).Error
+	return conn(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		var seq uint64
+		result := tx.Raw(
+			"UPDATE chats SET last_seq = last_seq + 1, history_version = history_version + 1, updated_at = now() WHERE id = ? RETURNING last_seq",
+			message.ChatID,
+		).Scan(&seq)
+
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+
+		message.Seq = seq
+		return tx.Create(message).Error
+	})
+}
+
+// CreateBatch appends messages to the chat in slice order with one multi-row
+// insert. The seq range is reserved by a single last_seq bump, so a batch is
+// as gapless as Create.
+func (m messageRepository) CreateBatch(ctx context.Context, chatID uint, messages []domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return conn(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		var last uint64
+		result := tx.Raw(
+			"UPDATE chats SET last_seq = last_seq + ?, history_version = history_version + 1, updated_at = now() WHERE id = ? RETURNING last_seq",
+			len(messages), chatID,
+		).Scan(&last)
+
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+
+		first := last - uint64(len(messages)) + 1
+		for i := range messages {
+			messages[i].ChatID = chatID
+			messages[i].Seq = first + uint64(i)
+		}
+		return tx.CreateInBatches(messages, importInsertBatch).Error
+	})
+}
+
+// Update edits the text of the message at message.ChatID and message.Seq.
+// The chat row is bumped first: edits change the chat's history, and taking
+// the chat lock before the message lock keeps the order used by Create.
+func (m messageRepository) Update(ctx context.Context, message *domain.Message, version uint64) error {
+	return conn(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("UPDATE chats SET history_version = history_version + 1, updated_at = now() WHERE id = ?", message.ChatID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+
+		query := tx.Model(message).Clauses(clause.Returning{}).
+			Where("chat_id = ? AND seq = ?", message.ChatID, message.Seq)
+		if version > 0 {
+			query = query.Where("version = ?", version)
+		}
+		result = query.Updates(map[string]any{
+			"text":      message.Text,
+			"version":   gorm.Expr("version + 1"),
+			"edited_at": gorm.Expr("now()"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		return missingMessage(tx, message.ChatID, message.Seq)
+	})
+}
+
+// Delete removes the message at chatID and seq, bumping the chat as Update does.
+func (m messageRepository) Delete(ctx context.Context, chatID uint, seq uint64, version uint64) error {
+	return conn(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("UPDATE chats SET history_version = history_version + 1, updated_at = now() WHERE id = ?", chatID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+
+		query := tx.Where("chat_id = ? AND seq = ?", chatID, seq)
+		if version > 0 {
+			query = query.Where("version = ?", version)
+		}
+		result = query.Delete(&domain.Message{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		return missingMessage(tx, chatID, seq)
+	})
+}
+
+func missingMessage(tx *gorm.DB, chatID uint, seq uint64) error {
+	var count int64
+	err := tx.Model(&domain.Message{}).Where("chat_id = ? AND seq = ?", chatID, seq).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return domain.ErrVersionMismatch
+	}
+	return domain.ErrNotFound
+}
+
+// GetByChatID returns a page of history in ascending seq order.
+func (m messageRepository) GetByChatID(ctx context.Context, chatID uint, cursor domain.MessageCursor) ([]domain.Message, error) {
+	var message []domain.Message
+
+	query := conn(ctx, m.db).Where("chat_id = ?", chatID).Limit(cursor.Limit)
+
+	if cursor.AfterSeq > 0 {
+		err := query.Where("seq > ?", cursor.AfterSeq).Order("seq ASC").Find(&message).Error
+		return message, err
+	}
+
+	if cursor.BeforeSeq > 0 {
+		query = query.Where("seq < ?", cursor.BeforeSeq)
+	}
+	if err := query.Order("seq DESC").Find(&message).Error; err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(message)
+	return message, nil
 }
 
-func (m messageRepository) GetByChatID(ctx context.Context, chatID uint, limit int) ([]domain.Message, error) {
+// GetLatestByChatIDs loads the latest limit messages of every chat in one
+// query, ordered by chat and ascending seq.
+func (m messageRepository) GetLatestByChatIDs(ctx context.Context, chatIDs []uint, limit int) ([]domain.Message, error) {
 	var message []domain.Message
 
-	err := m.db.WithContext(ctx).
-		Where("chat_id = ?", chatID).
-		Order("created_at DESC").
-		Limit(limit).
+	latest := conn(ctx, m.db).Model(&domain.Message{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY chat_id ORDER BY seq DESC) AS rank").
+		Where("chat_id IN ?", chatIDs)
+
+	err := conn(ctx, m.db).Table("(?) AS latest", latest).
+		Where("rank <= ?", limit).
+		Order("chat_id, seq").
 		Find(&message).Error
 	return message, err
 }
+
+// IterateByChatID yields the filtered history in ascending seq order. It
+// pages by seq instead of holding a cursor open, so memory stays constant
+// and no connection is pinned while the caller writes the messages out.
+func (m messageRepository) IterateByChatID(ctx context.Context, chatID uint, filter domain.HistoryFilter) iter.Seq2[domain.Message, error] {
+	return func(yield func(domain.Message, error) bool) {
+		var afterSeq uint64
+		for {
+			query := conn(ctx, m.db).Where("chat_id = ? AND seq > ?", chatID, afterSeq)
+			if filter.UpToSeq > 0 {
+				query = query.Where("seq <= ?", filter.UpToSeq)
+			}
+			if !filter.From.IsZero() {
+				query = query.Where("created_at >= ?", filter.From)
+			}
+			if !filter.To.IsZero() {
+				query = query.Where("created_at < ?", filter.To)
+			}
+
+			var page []domain.Message
+			if err := query.Order("seq ASC").Limit(iteratePageSize).Find(&page).Error; err != nil {
+				yield(domain.Message{}, err)
+				return
+			}
+
+			for _, message := range page {
+				if !yield(message, nil) {
+					return
+				}
+			}
+			if len(page) < iteratePageSize {
+				return
+			}
+			afterSeq = page[len(page)-1].Seq
+		}
+	}
+}
+
+func (m messageRepository) CountCreatedBefore(ctx context.Context, chatID uint, before time.Time) (int64, error) {
+	var count int64
+	err := createdBefore(conn(ctx, m.db).Model(&domain.Message{}), chatID, before).Count(&count).Error
+	return count, err
+}
+
+// DeleteCreatedBefore removes old history. The affected chats are bumped
+// first, as for Update, so their ETags change with the history.
+func (m messageRepository) DeleteCreatedBefore(ctx context.Context, chatID uint, before time.Time) (int64, error) {
+	var deleted int64
+	err := conn(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		affected := createdBefore(tx.Model(&domain.Message{}), chatID, before).Distinct("chat_id")
+		err := tx.Model(&domain.Chat{}).
+			Where("id IN (?)", affected).
+			Updates(map[string]any{
+				"history_version": gorm.Expr("history_version + 1"),
+				"updated_at":      gorm.Expr("now()"),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		result := createdBefore(tx, chatID, before).Delete(&domain.Message{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+func createdBefore(query *gorm.DB, chatID uint, before time.Time) *gorm.DB {
+	query = query.Where("created_at < ?", before)
+	if chatID > 0 {
+		query = query.Where("chat_id = ?", chatID)
+	}
+	return query
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"chats/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (o outboxRepository) Add(ctx context.Context, event domain.Event) error {
+	entry, err := domain.NewOutboxEntry(event)
+	if err != nil {
+		return err
+	}
+	return conn(ctx, o.db).Create(entry).Error
+}
+
+// FetchPending locks up to limit due entries. It must run inside WithinTx so
+// the locks are held until the entries are marked; concurrent relays skip them.
+func (o outboxRepository) FetchPending(ctx context.Context, limit int) ([]domain.OutboxEntry, error) {
+	var entries []domain.OutboxEntry
+
+	err := conn(ctx, o.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("processed_at IS NULL AND available_at <= NOW()").
+		Order("id").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+func (o outboxRepository) MarkProcessed(ctx context.Context, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return conn(ctx, o.db).Model(&domain.OutboxEntry{}).
+		Where("id IN ?", ids).
+		Update("processed_at", time.Now()).Error
+}
+
+func (o outboxRepository) MarkFailed(ctx context.Context, id uint64, cause error, retryAt time.Time) error {
+	return conn(ctx, o.db).Model(&domain.OutboxEntry{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   cause.Error(),
+			"available_at": retryAt,
+		}).Error
+}
+
+func (o outboxRepository) DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, o.db).
+		Where("processed_at IS NOT NULL AND processed_at < ?", before).
+		Delete(&domain.OutboxEntry{})
+	return result.RowsAffected, result.Error
+}
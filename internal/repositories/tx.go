@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"chats/internal/database"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+type gormTxManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &gormTxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction carried by ctx. Repositories called with
+// that ctx join the transaction; nested calls reuse the outer one.
+func (m gormTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	// Statement errors are translated already; this covers BEGIN and COMMIT.
+	return database.TranslateError(m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	}))
+}
+
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
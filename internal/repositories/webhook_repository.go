@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+func (w webhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	return conn(ctx, w.db).Create(webhook).Error
+}
+
+func (w webhookRepository) GetByID(ctx context.Context, id uint) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+
+	if err := conn(ctx, w.db).First(&webhook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (w webhookRepository) List(ctx context.Context) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+
+	err := conn(ctx, w.db).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (w webhookRepository) Delete(ctx context.Context, id uint) error {
+	result := conn(ctx, w.db).Delete(&domain.Webhook{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (w webhookRepository) SetActive(ctx context.Context, id uint, active bool) error {
+	updates := map[string]any{"active": active}
+	if active {
+		updates["consecutive_failures"] = 0
+		updates["disabled_at"] = nil
+	} else {
+		updates["disabled_at"] = time.Now()
+	}
+
+	result := conn(ctx, w.db).Model(&domain.Webhook{}).Where("id = ?", id).Updates(updates)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (w webhookRepository) FindSubscribed(ctx context.Context, chatID uint, eventType domain.EventType) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+
+	filter, err := domain.EventTypeList{eventType}.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn(ctx, w.db).
+		Where("active").
+		Where("chat_id IS NULL OR chat_id = ?", chatID).
+		Where("event_types @> ?::jsonb", filter).
+		Order("id").
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (w webhookRepository) RecordSuccess(ctx context.Context, id uint) error {
+	return conn(ctx, w.db).Model(&domain.Webhook{}).
+		Where("id = ? AND consecutive_failures > 0", id).
+		Update("consecutive_failures", 0).Error
+}
+
+func (w webhookRepository) RecordFailure(ctx context.Context, id uint, disableAfter int) (bool, error) {
+	var disabled bool
+
+	err := conn(ctx, w.db).Raw(`
+		UPDATE webhooks
+		SET consecutive_failures = consecutive_failures + 1,
+		    active = active AND consecutive_failures + 1 < @limit,
+		    disabled_at = CASE
+		        WHEN active AND consecutive_failures + 1 >= @limit THEN NOW()
+		        ELSE disabled_at
+		    END
+		WHERE id = @id
+		RETURNING NOT active`,
+		map[string]any{"id": id, "limit": disableAfter},
+	).Scan(&disabled).Error
+
+	return disabled, err
+}
+
+func (w webhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return conn(ctx, w.db).Create(delivery).Error
+}
+
+func (w webhookRepository) ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+
+	err := conn(ctx, w.db).
+		Where("webhook_id = ?", webhookID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (w webhookRepository) CreateJob(ctx context.Context, job *domain.WebhookJob) error {
+	return conn(ctx, w.db).Create(job).Error
+}
+
+func (w webhookRepository) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookJob, error) {
+	var jobs []domain.WebhookJob
+
+	err := conn(ctx, w.db).Raw(`
+		UPDATE webhook_jobs
+		SET available_at = @until
+		WHERE id IN (
+		    SELECT id FROM webhook_jobs
+		    WHERE available_at <= NOW()
+		    ORDER BY available_at, id
+		    LIMIT @limit
+		    FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		map[string]any{"until": time.Now().Add(lease), "limit": limit},
+	).Scan(&jobs).Error
+	return jobs, err
+}
+
+func (w webhookRepository) RescheduleJob(ctx context.Context, id uint64, attempt int, at time.Time) error {
+	return conn(ctx, w.db).Model(&domain.WebhookJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"attempt": attempt, "available_at": at}).Error
+}
+
+func (w webhookRepository) DeleteJob(ctx context.Context, id uint64) error {
+	return conn(ctx, w.db).Delete(&domain.WebhookJob{}, id).Error
+}
+
+func (w webhookRepository) GetDelivery(ctx context.Context, webhookID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+
+	err := conn(ctx, w.db).
+		Where("webhook_id = ? AND id = ?", webhookID, deliveryID).
+		First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
@@ -1,16 +1,108 @@
 package route
 
 import (
+	"chats/internal/apiversion"
 	"chats/internal/handlers"
-	"encoding/json"
-	"log"
+	"chats/internal/middleware"
+	"chats/internal/openapi"
+	"chats/internal/problem"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func SetupQuestionRoutes(chatHandler *handlers.ChatHandler, messageHandler *handlers.MessageHandler) http.Handler {
+type Deps struct {
+	ChatHandler    *handlers.ChatHandler
+	MessageHandler *handlers.MessageHandler
+	WebhookHandler *handlers.WebhookHandler
+	ImportHandler  *handlers.ImportHandler
+	ExportHandler  *handlers.ExportHandler
+	BatchHandler   *handlers.BatchHandler
+	HealthHandler  *handlers.HealthHandler
+	// GraphQL is mounted at /graphql when set.
+	GraphQL http.Handler
+
+	AdminToken string
+	// Deprecation is announced on v1 responses.
+	Deprecation apiversion.Deprecation
+	// RequireIfMatch makes If-Match mandatory on PATCH and DELETE.
+	RequireIfMatch bool
+	// MaxBodyBytes caps the JSON bodies read by request validation; zero
+	// leaves them uncapped.
+	MaxBodyBytes int64
+	// Idempotency wraps the create endpoints; nil disables Idempotency-Key support.
+	Idempotency func(http.Handler) http.Handler
+	// Compression wraps every route; nil disables response compression.
+	Compression func(http.Handler) http.Handler
+	// ReadYourWrites keeps callers on the primary database after they
+	// write; nil leaves reads to the replicas, if any.
+	ReadYourWrites func(http.Handler) http.Handler
+	// Deadline bounds every request except exports, imports and
+	// subscriptions; nil leaves requests unbounded.
+	Deadline func(http.Handler) http.Handler
+	// RealIP takes the client address from trusted proxies' X-Forwarded-For;
+	// nil keeps RemoteAddr.
+	RealIP func(http.Handler) http.Handler
+	// Tracing starts a span for every request; nil disables it.
+	Tracing func(http.Handler) http.Handler
+	// Metrics instruments every request and MetricsHandler is mounted at
+	// /metrics; both are optional.
+	Metrics        func(http.Handler) http.Handler
+	MetricsHandler http.Handler
+}
+
+func SetupQuestionRoutes(deps Deps) http.Handler {
+	chatHandler, messageHandler, webhookHandler := deps.ChatHandler, deps.MessageHandler, deps.WebhookHandler
+
+	passthrough := func(next http.Handler) http.Handler { return next }
+
+	idempotent := deps.Idempotency
+	if idempotent == nil {
+		idempotent = passthrough
+	}
+
+	ifMatch := passthrough
+	if deps.RequireIfMatch {
+		ifMatch = middleware.RequireIfMatch
+	}
+
+	compress := deps.Compression
+	if compress == nil {
+		compress = passthrough
+	}
+
+	instrument := deps.Metrics
+	if instrument == nil {
+		instrument = passthrough
+	}
+
+	trace := deps.Tracing
+	if trace == nil {
+		trace = passthrough
+	}
+
+	readYourWrites := deps.ReadYourWrites
+	if readYourWrites == nil {
+		readYourWrites = passthrough
+	}
+
+	deadline := deps.Deadline
+	if deadline == nil {
+		deadline = passthrough
+	}
+
+	realIP := deps.RealIP
+	if realIP == nil {
+		realIP = passthrough
+	}
+
+	spec := openapi.Default()
+	validate := spec.Validate(deps.MaxBodyBytes)
+
 	r := chi.NewRouter()
+	r.Use(realIP, middleware.RequestID, trace, middleware.Logger, instrument, middleware.Recover, readYourWrites, compress)
+	r.NotFound(problem.NotFound)
+	r.MethodNotAllowed(problem.MethodNotAllowed)
 
 	//r.Route("/api", func(r chi.Router) {
 	//	r.Get("/chats/{id}", chatHandler.HandleGetChat)
@@ -20,26 +112,86 @@ func SetupQuestionRoutes(chatHandler *handlers.ChatHandler, messageHandler *hand
 	//	r.Post("/chats/{id}/messages", messageHandler.HandleCreateMessage)
 	//})
 
-	r.Route("/api", func(r chi.Router) {
+	// resources are mounted once per version; handlers pick the response
+	// shape from the version the middleware stored in the request context.
+	resources := func(r chi.Router) {
 		r.Route("/chats", func(r chi.Router) {
-			r.Post("/", chatHandler.HandleCreateChat)
+			r.With(validate, deadline, idempotent).Post("/", chatHandler.HandleCreateChat)
 			r.Route("/{id}", func(r chi.Router) {
-				r.Get("/", chatHandler.HandleGetChat)
-				r.Delete("/", chatHandler.HandleDeleteChat)
-				r.Post("/messages", messageHandler.HandleCreateMessage)
+				r.Group(func(r chi.Router) {
+					r.Use(validate, deadline)
+
+					r.Get("/", chatHandler.HandleGetChat)
+					r.With(ifMatch).Patch("/", chatHandler.HandleUpdateChat)
+					r.With(ifMatch).Delete("/", chatHandler.HandleDeleteChat)
+					r.With(idempotent).Post("/messages", messageHandler.HandleCreateMessage)
+					r.Get("/messages", messageHandler.HandleListMessages)
+					r.With(ifMatch).Patch("/messages/{seq}", messageHandler.HandleUpdateMessage)
+					r.With(ifMatch).Delete("/messages/{seq}", messageHandler.HandleDeleteMessage)
+				})
+
+				// Exports and imports run as long as the transfer takes.
+				// The admin check comes first, as under /admin, so the body of
+				// an unauthorized import is never read.
+				r.With(validate).Get("/export", deps.ExportHandler.HandleExportChat)
+				r.With(middleware.RequireAdmin(deps.AdminToken), validate).Post("/messages:import", deps.ImportHandler.HandleImportMessages)
 			})
 		})
-	})
 
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Health check from %s", r.RemoteAddr)
-		w.Header().Set("Content-Type", "application/json")
-		err := json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-		if err != nil {
-			log.Printf("Error encoding response: %v", err)
-			return
-		}
+		r.With(deadline, validate).Post("/batch", deps.BatchHandler.HandleBatch)
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.RequireAdmin(deps.AdminToken))
+			r.Use(deadline, validate)
+
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Post("/", webhookHandler.HandleCreateWebhook)
+				r.Get("/", webhookHandler.HandleListWebhooks)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", webhookHandler.HandleGetWebhook)
+					r.Delete("/", webhookHandler.HandleDeleteWebhook)
+					r.Post("/enable", webhookHandler.HandleEnableWebhook)
+					r.Get("/deliveries", webhookHandler.HandleListDeliveries)
+					r.Get("/deliveries/{deliveryID}", webhookHandler.HandleGetDelivery)
+					r.Post("/deliveries/{deliveryID}/redeliver", webhookHandler.HandleRedeliver)
+				})
+			})
+		})
+	}
+
+	deprecateV1 := apiversion.Deprecate(apiversion.V1, deps.Deprecation)
+
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/openapi.json", spec.ServeHTTP)
+
+		// Unversioned paths negotiate the version through Accept, defaulting to v1.
+		r.Group(func(r chi.Router) {
+			r.Use(apiversion.Negotiate, deprecateV1)
+			resources(r)
+		})
+		r.Route("/v1", func(r chi.Router) {
+			r.Use(apiversion.Use(apiversion.V1), deprecateV1)
+			resources(r)
+		})
+		r.Route("/v2", func(r chi.Router) {
+			r.Use(apiversion.Use(apiversion.V2))
+			resources(r)
+		})
 	})
 
+	if deps.GraphQL != nil {
+		r.With(deadline).Get("/graphql", deps.GraphQL.ServeHTTP)
+		r.With(deadline).Post("/graphql", deps.GraphQL.ServeHTTP)
+	}
+
+	if deps.MetricsHandler != nil {
+		r.Get("/metrics", deps.MetricsHandler.ServeHTTP)
+	}
+
+	// /health predates the split probes and stays an alias of liveness.
+	r.Get("/health", deps.HealthHandler.HandleLive)
+	r.Get("/health/live", deps.HealthHandler.HandleLive)
+	r.Get("/health/ready", deps.HealthHandler.HandleReady)
+
 	return r
 }
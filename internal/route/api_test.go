@@ -0,0 +1,55 @@
+package route
+
+import (
+	"chats/internal/handlers"
+	"chats/internal/openapi"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutesAreDocumented(t *testing.T) {
+	router := SetupQuestionRoutes(Deps{
+		ChatHandler:    handlers.NewChatHandler(nil),
+		MessageHandler: handlers.NewMessageHandler(nil),
+		WebhookHandler: handlers.NewWebhookHandler(nil),
+		ImportHandler:  handlers.NewImportHandler(nil),
+		ExportHandler:  handlers.NewExportHandler(nil),
+		BatchHandler:   handlers.NewBatchHandler(nil, nil, nil, nil, handlers.BatchOptions{}),
+		HealthHandler:  handlers.NewHealthHandler(nil),
+		GraphQL:        http.NotFoundHandler(),
+		MetricsHandler: http.NotFoundHandler(),
+	})
+
+	spec := openapi.Default()
+	walked := 0
+	err := chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		walked++
+		path := strings.TrimSuffix(route, "/")
+		assert.True(t, spec.Has(method, path), "%s %s is not in internal/openapi/openapi.json", method, path)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, walked)
+}
+
+func TestImportChecksAdminBeforeValidation(t *testing.T) {
+	router := SetupQuestionRoutes(Deps{
+		ImportHandler: handlers.NewImportHandler(nil),
+		HealthHandler: handlers.NewHealthHandler(nil),
+		AdminToken:    "secret",
+	})
+
+	req := httptest.NewRequest("POST", "/api/chats/1/messages:import?offset=-1", strings.NewReader(`{"text": "привет"}`))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
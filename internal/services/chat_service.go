@@ -2,34 +2,38 @@ package services
 
 import (
 	"chats/internal/domain"
+	"chats/internal/limits"
 	"chats/internal/repositories"
 	"context"
 	"strings"
 )
 
 type chatService struct {
-	chatRepo repositories.ChatRepository
+	chatRepo   repositories.ChatRepository
+	txManager  repositories.TxManager
+	outboxRepo repositories.OutboxRepository
 }
 
-func NewChatService(chatRepo repositories.ChatRepository) ChatService {
-	return &chatService{chatRepo: chatRepo}
+func NewChatService(chatRepo repositories.ChatRepository, txManager repositories.TxManager, outboxRepo repositories.OutboxRepository) ChatService {
+	return &chatService{chatRepo: chatRepo, txManager: txManager, outboxRepo: outboxRepo}
 }
 
 func (c chatService) CreateChat(ctx context.Context, title string) (*domain.Chat, error) {
-	title = strings.TrimSpace(title)
-
-	if title == "" {
-		return nil, domain.ErrInvalidInput
-	}
-
-	if len(title) > 200 {
-		return nil, domain.ErrInvalidInput
+	title, err := validateTitle(title)
+	if err != nil {
+		return nil, err
 	}
 
 	chat := &domain.Chat{
 		Title: title,
 	}
-	if err := c.chatRepo.Create(ctx, chat); err != nil {
+	err = c.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Create(ctx, chat); err != nil {
+			return err
+		}
+		return recordEvent(ctx, c.outboxRepo, domain.EventChatCreated, chat.ID, chat)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return chat, nil
@@ -39,8 +43,46 @@ func (c chatService) GetChat(ctx context.Context, id uint, limit int) (*domain.C
 	return c.chatRepo.GetByID(ctx, id, true, limit)
 }
 
-func (c chatService) DeleteChat(ctx context.Context, id uint) error {
-	return c.chatRepo.Delete(ctx, id)
+// GetChatState returns the chat row alone. Its LastSeq and UpdatedAt tell
+// whether a client's copy is current without reading any message.
+func (c chatService) GetChatState(ctx context.Context, id uint) (*domain.Chat, error) {
+	return c.chatRepo.GetByID(ctx, id, false, 0)
+}
+
+// GetChats returns the chats that exist among ids, without messages.
+func (c chatService) GetChats(ctx context.Context, ids []uint) ([]domain.Chat, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return c.chatRepo.GetByIDs(ctx, ids)
+}
+
+func (c chatService) UpdateChat(ctx context.Context, id uint, title string, version uint64) (*domain.Chat, error) {
+	title, err := validateTitle(title)
+	if err != nil {
+		return nil, err
+	}
+
+	chat := &domain.Chat{ID: id, Title: title}
+	err = c.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Update(ctx, chat, version); err != nil {
+			return err
+		}
+		return recordEvent(ctx, c.outboxRepo, domain.EventChatUpdated, chat.ID, chat)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+func (c chatService) DeleteChat(ctx context.Context, id uint, version uint64) error {
+	return c.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return recordEvent(ctx, c.outboxRepo, domain.EventChatDeleted, id, map[string]uint{"id": id})
+	})
 }
 
 func (c chatService) ValidateChatExists(ctx context.Context, id uint) error {
@@ -53,3 +95,21 @@ func (c chatService) ValidateChatExists(ctx context.Context, id uint) error {
 	}
 	return nil
 }
+
+func validateTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" || len(title) > limits.Current().MaxTitleLength {
+		return "", domain.ErrInvalidInput
+	}
+	return title, nil
+}
+
+// recordEvent stores the event in the outbox. Callers run it in the same
+// transaction as the change, so the event exists if and only if the change commits.
+func recordEvent(ctx context.Context, outboxRepo repositories.OutboxRepository, eventType domain.EventType, chatID uint, data any) error {
+	event, err := domain.NewEvent(eventType, chatID, data)
+	if err != nil {
+		return err
+	}
+	return outboxRepo.Add(ctx, event)
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"chats/internal/domain"
+	"chats/internal/repositories"
+	"context"
+	"iter"
+)
+
+type exportService struct {
+	chatRepo    repositories.ChatRepository
+	messageRepo repositories.MessageRepository
+}
+
+func NewExportService(chatRepo repositories.ChatRepository, messageRepo repositories.MessageRepository) ExportService {
+	return &exportService{
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+	}
+}
+
+// ExportChat stops the history at the chat's last_seq as read here, so
+// messages sent while a long export is being written are left out.
+func (e exportService) ExportChat(ctx context.Context, chatID uint, filter domain.HistoryFilter) (*domain.Chat, iter.Seq2[domain.Message, error], error) {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, nil, domain.ErrInvalidInput
+	}
+
+	chat, err := e.chatRepo.GetByID(ctx, chatID, false, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter.UpToSeq = chat.LastSeq
+	if chat.LastSeq == 0 {
+		return chat, func(func(domain.Message, error) bool) {}, nil
+	}
+	return chat, e.messageRepo.IterateByChatID(ctx, chatID, filter), nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bufio"
+	"chats/internal/config"
+	"chats/internal/domain"
+	"chats/internal/limits"
+	"chats/internal/repositories"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	maxAuthorLength = 200
+	// maxImportLine bounds a single NDJSON line; a line with the longest
+	// allowed text is far below it even when fully escaped.
+	maxImportLine = 1 << 20
+)
+
+type importService struct {
+	messageRepo repositories.MessageRepository
+	chatService ChatService
+	txManager   repositories.TxManager
+	cfg         config.ImportConfig
+}
+
+func NewImportService(
+	messageRepo repositories.MessageRepository,
+	chatService ChatService,
+	txManager repositories.TxManager,
+	cfg config.ImportConfig,
+) ImportService {
+	return &importService{
+		messageRepo: messageRepo,
+		chatService: chatService,
+		txManager:   txManager,
+		cfg:         cfg,
+	}
+}
+
+type importLine struct {
+	Text      string    `json:"text"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImportMessages appends every valid line of body to the chat, committing a
+// transaction per batch. The first offset lines are skipped so an
+// interrupted import can be resent as is. Invalid lines are reported and
+// skipped; a broken stream or a failed batch stops the import, and the
+// returned report still tells how far it got.
+//
+// Imported history does not produce message.created events: replaying old
+// messages to webhooks and subscribers is not what a migration wants.
+func (s importService) ImportMessages(ctx context.Context, chatID uint, body io.Reader, offset int) (*domain.ImportReport, error) {
+	if offset < 0 {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := s.chatService.ValidateChatExists(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	report := &domain.ImportReport{Errors: []domain.ImportLineError{}}
+	batch := make([]domain.Message, 0, s.cfg.BatchSize)
+	line := 0
+
+	flush := func() error {
+		if len(batch) > 0 {
+			err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+				return s.messageRepo.CreateBatch(ctx, chatID, batch)
+			})
+			if err != nil {
+				return err
+			}
+			report.Imported += len(batch)
+			batch = batch[:0]
+		}
+		report.NextOffset = line
+		return nil
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	for scanner.Scan() {
+		line++
+		if line <= offset {
+			report.Skipped++
+			report.NextOffset = line
+			continue
+		}
+
+		message, err := parseImportLine(scanner.Bytes())
+		if err != nil {
+			report.Failed++
+			if len(report.Errors) < s.cfg.MaxErrors {
+				report.Errors = append(report.Errors, domain.ImportLineError{Line: line, Error: err.Error()})
+			} else {
+				report.ErrorsTruncated = true
+			}
+			continue
+		}
+		if message == nil {
+			continue
+		}
+
+		batch = append(batch, *message)
+		if len(batch) >= s.cfg.BatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	// Lines read before the stream broke are still committed.
+	if err := flush(); err != nil {
+		return report, err
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return report, domain.NewError(domain.ErrInvalidInput, "line %d is longer than %d bytes", line+1, maxImportLine)
+		}
+		return report, err
+	}
+
+	return report, nil
+}
+
+// parseImportLine returns nil for a blank line. A missing created_at falls
+// back to the import time.
+func parseImportLine(data []byte) (*domain.Message, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var in importLine
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, errors.New("not a JSON object")
+	}
+
+	text := strings.TrimSpace(in.Text)
+	if text == "" {
+		return nil, errors.New("text is required")
+	}
+	if maxLength := limits.Current().MaxMessageLength; len(text) > maxLength {
+		return nil, fmt.Errorf("text must be %d characters or less", maxLength)
+	}
+
+	author := strings.TrimSpace(in.Author)
+	if len(author) > maxAuthorLength {
+		return nil, fmt.Errorf("author must be %d characters or less", maxAuthorLength)
+	}
+
+	return &domain.Message{
+		Text:      text,
+		Author:    author,
+		CreatedAt: in.CreatedAt,
+	}, nil
+}
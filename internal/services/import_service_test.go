@@ -0,0 +1,156 @@
+package services
+
+import (
+	"chats/internal/config"
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMessageRepo struct {
+	batches [][]domain.Message
+	// failOnBatch makes the n-th CreateBatch call (1-based) fail.
+	failOnBatch int
+}
+
+func (f *fakeMessageRepo) Create(context.Context, *domain.Message) error { return nil }
+
+func (f *fakeMessageRepo) CreateBatch(_ context.Context, _ uint, messages []domain.Message) error {
+	if len(f.batches)+1 == f.failOnBatch {
+		return errors.New("pq: connection reset by peer")
+	}
+	f.batches = append(f.batches, append([]domain.Message(nil), messages...))
+	return nil
+}
+
+func (f *fakeMessageRepo) Update(context.Context, *domain.Message, uint64) error { return nil }
+
+func (f *fakeMessageRepo) Delete(context.Context, uint, uint64, uint64) error { return nil }
+
+func (f *fakeMessageRepo) GetByChatID(context.Context, uint, domain.MessageCursor) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func (f *fakeMessageRepo) GetLatestByChatIDs(context.Context, []uint, int) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func (f *fakeMessageRepo) IterateByChatID(context.Context, uint, domain.HistoryFilter) iter.Seq2[domain.Message, error] {
+	return nil
+}
+
+func (f *fakeMessageRepo) CountCreatedBefore(context.Context, uint, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeMessageRepo) DeleteCreatedBefore(context.Context, uint, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeMessageRepo) texts() []string {
+	var texts []string
+	for _, batch := range f.batches {
+		for _, m := range batch {
+			texts = append(texts, m.Text)
+		}
+	}
+	return texts
+}
+
+type fakeChatService struct {
+	ChatService
+}
+
+func (fakeChatService) ValidateChatExists(_ context.Context, id uint) error {
+	if id != 1 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+type passthroughTx struct{}
+
+func (passthroughTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newTestImportService(repo *fakeMessageRepo) ImportService {
+	return NewImportService(repo, fakeChatService{}, passthroughTx{}, config.ImportConfig{BatchSize: 2, MaxErrors: 1})
+}
+
+const importBody = `{"text": "one", "author": "ann", "created_at": "2019-05-01T10:00:00Z"}
+{"text": "two"}
+not json
+
+{"text": "   "}
+{"text": "three"}
+`
+
+func TestImportService_ImportsValidLinesInBatches(t *testing.T) {
+	repo := &fakeMessageRepo{}
+	report, err := newTestImportService(repo).ImportMessages(context.Background(), 1, strings.NewReader(importBody), 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"one", "two", "three"}, repo.texts())
+	assert.Len(t, repo.batches, 2)
+	assert.Equal(t, "ann", repo.batches[0][0].Author)
+	assert.Equal(t, time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC), repo.batches[0][0].CreatedAt)
+
+	assert.Equal(t, 3, report.Imported)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, 6, report.NextOffset)
+	assert.Equal(t, []domain.ImportLineError{{Line: 3, Error: "not a JSON object"}}, report.Errors)
+	assert.True(t, report.ErrorsTruncated)
+}
+
+func TestImportService_ResumesFromOffset(t *testing.T) {
+	repo := &fakeMessageRepo{}
+	report, err := newTestImportService(repo).ImportMessages(context.Background(), 1, strings.NewReader(importBody), 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"three"}, repo.texts())
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, 6, report.NextOffset)
+}
+
+func TestImportService_ReportsProgressOnFailure(t *testing.T) {
+	repo := &fakeMessageRepo{failOnBatch: 2}
+	report, err := newTestImportService(repo).ImportMessages(context.Background(), 1, strings.NewReader(importBody), 0)
+	require.Error(t, err)
+
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 2, report.NextOffset)
+}
+
+func TestImportService_CommitsWhatWasReadBeforeTheStreamBroke(t *testing.T) {
+	repo := &fakeMessageRepo{}
+	body := io.MultiReader(strings.NewReader("{\"text\": \"one\"}\n"), failingReader{})
+	report, err := newTestImportService(repo).ImportMessages(context.Background(), 1, body, 0)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	assert.Equal(t, []string{"one"}, repo.texts())
+	assert.Equal(t, 1, report.NextOffset)
+}
+
+func TestImportService_RejectsOverlongLines(t *testing.T) {
+	body := strings.NewReader(`{"text": "` + strings.Repeat("a", maxImportLine) + `"}`)
+	_, err := newTestImportService(&fakeMessageRepo{}).ImportMessages(context.Background(), 1, body, 0)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestImportService_UnknownChat(t *testing.T) {
+	_, err := newTestImportService(&fakeMessageRepo{}).ImportMessages(context.Background(), 2, strings.NewReader(importBody), 0)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, io.ErrUnexpectedEOF }
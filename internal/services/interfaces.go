@@ -3,15 +3,67 @@ package services
 import (
 	"chats/internal/domain"
 	"context"
+	"io"
+	"iter"
 )
 
 type ChatService interface {
 	CreateChat(ctx context.Context, title string) (*domain.Chat, error)
 	GetChat(ctx context.Context, id uint, limit int) (*domain.Chat, error)
-	DeleteChat(ctx context.Context, id uint) error
+	GetChatState(ctx context.Context, id uint) (*domain.Chat, error)
+	GetChats(ctx context.Context, ids []uint) ([]domain.Chat, error)
+	// UpdateChat and DeleteChat apply only while the chat is at version;
+	// zero skips the check.
+	UpdateChat(ctx context.Context, id uint, title string, version uint64) (*domain.Chat, error)
+	DeleteChat(ctx context.Context, id uint, version uint64) error
 	ValidateChatExists(ctx context.Context, id uint) error
 }
 
 type MessageService interface {
 	CreateMessage(ctx context.Context, chatID uint, message string) (*domain.Message, error)
+	// UpdateMessage applies only while the message is at version; zero skips the check.
+	UpdateMessage(ctx context.Context, chatID uint, seq uint64, text string, version uint64) (*domain.Message, error)
+	// DeleteMessage applies only while the message is at version; zero skips the check.
+	DeleteMessage(ctx context.Context, chatID uint, seq uint64, version uint64) error
+	ListMessages(ctx context.Context, chatID uint, cursor domain.MessageCursor) ([]domain.Message, error)
+	ListLatestMessages(ctx context.Context, chatIDs []uint, limit int) (map[uint][]domain.Message, error)
+}
+
+// ImportService loads message history from NDJSON, keeping the original
+// timestamps and authors.
+type ImportService interface {
+	ImportMessages(ctx context.Context, chatID uint, body io.Reader, offset int) (*domain.ImportReport, error)
+}
+
+// ExportService streams a chat's history for transcripts. The chat is
+// returned before any message is read, so callers can write a header first.
+type ExportService interface {
+	ExportChat(ctx context.Context, chatID uint, filter domain.HistoryFilter) (*domain.Chat, iter.Seq2[domain.Message, error], error)
+}
+
+// Transactor runs fn in one transaction. Services called with the ctx it
+// passes join that transaction instead of opening their own.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+type WebhookDispatcher interface {
+	ValidateTarget(ctx context.Context, rawURL string) error
+	Enqueue(ctx context.Context, webhook domain.Webhook, eventID string, eventType domain.EventType, payload []byte) error
+}
+
+type WebhookService interface {
+	EventPublisher
+	CreateWebhook(ctx context.Context, url string, chatID *uint, eventTypes []domain.EventType) (*domain.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	GetWebhook(ctx context.Context, id uint) (*domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint) error
+	EnableWebhook(ctx context.Context, id uint) error
+	ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]domain.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, deliveryID uint) (*domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID uint) error
 }
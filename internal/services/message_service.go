@@ -2,6 +2,7 @@ package services
 
 import (
 	"chats/internal/domain"
+	"chats/internal/limits"
 	"chats/internal/repositories"
 	"context"
 	"strings"
@@ -10,12 +11,21 @@ import (
 type messageService struct {
 	messageRepo repositories.MessageRepository
 	chatService ChatService
+	txManager   repositories.TxManager
+	outboxRepo  repositories.OutboxRepository
 }
 
-func NewMessageService(messageRepo repositories.MessageRepository, chatService ChatService) MessageService {
+func NewMessageService(
+	messageRepo repositories.MessageRepository,
+	chatService ChatService,
+	txManager repositories.TxManager,
+	outboxRepo repositories.OutboxRepository,
+) MessageService {
 	return &messageService{
 		messageRepo: messageRepo,
 		chatService: chatService,
+		txManager:   txManager,
+		outboxRepo:  outboxRepo,
 	}
 }
 
@@ -24,19 +34,101 @@ func (m messageService) CreateMessage(ctx context.Context, chatID uint, text str
 		return nil, err
 	}
 
-	text = strings.TrimSpace(text)
-	if text == "" {
-		return nil, domain.ErrInvalidInput
+	text, err := validateText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &domain.Message{
+		ChatID: chatID,
+		Text:   text,
+	}
+
+	err = m.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := m.messageRepo.Create(ctx, message); err != nil {
+			return err
+		}
+		return recordEvent(ctx, m.outboxRepo, domain.EventMessageCreated, chatID, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func (m messageService) UpdateMessage(ctx context.Context, chatID uint, seq uint64, text string, version uint64) (*domain.Message, error) {
+	text, err := validateText(text)
+	if err != nil {
+		return nil, err
 	}
 
 	message := &domain.Message{
 		ChatID: chatID,
+		Seq:    seq,
 		Text:   text,
 	}
 
-	if err := m.messageRepo.Create(ctx, message); err != nil {
+	err = m.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := m.messageRepo.Update(ctx, message, version); err != nil {
+			return err
+		}
+		return recordEvent(ctx, m.outboxRepo, domain.EventMessageUpdated, chatID, message)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return message, nil
 }
+
+func (m messageService) DeleteMessage(ctx context.Context, chatID uint, seq uint64, version uint64) error {
+	return m.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := m.messageRepo.Delete(ctx, chatID, seq, version); err != nil {
+			return err
+		}
+		return recordEvent(ctx, m.outboxRepo, domain.EventMessageDeleted, chatID, map[string]any{"chat_id": chatID, "seq": seq})
+	})
+}
+
+func (m messageService) ListMessages(ctx context.Context, chatID uint, cursor domain.MessageCursor) ([]domain.Message, error) {
+	if cursor.AfterSeq > 0 && cursor.BeforeSeq > 0 {
+		return nil, domain.ErrInvalidInput
+	}
+
+	if err := m.chatService.ValidateChatExists(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	return m.messageRepo.GetByChatID(ctx, chatID, cursor)
+}
+
+// ListLatestMessages returns the latest messages of several chats at once,
+// in ascending seq order per chat. Unknown chats are simply absent.
+func (m messageService) ListLatestMessages(ctx context.Context, chatIDs []uint, limit int) (map[uint][]domain.Message, error) {
+	result := make(map[uint][]domain.Message, len(chatIDs))
+	if len(chatIDs) == 0 {
+		return result, nil
+	}
+
+	messages, err := m.messageRepo.GetLatestByChatIDs(ctx, chatIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range messages {
+		result[message.ChatID] = append(result[message.ChatID], message)
+	}
+	return result, nil
+}
+
+func validateText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", domain.ErrEmptyText
+	}
+	if len(text) > limits.Current().MaxMessageLength {
+		return "", domain.ErrTextTooLong
+	}
+	return text, nil
+}
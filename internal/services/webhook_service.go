@@ -0,0 +1,137 @@
+package services
+
+import (
+	"chats/internal/domain"
+	"chats/internal/repositories"
+	"chats/internal/webhooks"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+type webhookService struct {
+	webhookRepo repositories.WebhookRepository
+	chatRepo    repositories.ChatRepository
+	dispatcher  WebhookDispatcher
+}
+
+func NewWebhookService(webhookRepo repositories.WebhookRepository, chatRepo repositories.ChatRepository, dispatcher WebhookDispatcher) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		chatRepo:    chatRepo,
+		dispatcher:  dispatcher,
+	}
+}
+
+func (s webhookService) CreateWebhook(ctx context.Context, url string, chatID *uint, eventTypes []domain.EventType) (*domain.Webhook, error) {
+	if len(eventTypes) == 0 {
+		return nil, domain.NewError(domain.ErrInvalidInput, "at least one event type is required")
+	}
+	for _, t := range eventTypes {
+		if !t.Valid() {
+			return nil, domain.NewError(domain.ErrInvalidInput, "unknown event type %q", t)
+		}
+	}
+
+	if err := s.dispatcher.ValidateTarget(ctx, url); err != nil {
+		return nil, err
+	}
+
+	if chatID != nil {
+		exists, err := s.chatRepo.Exists(ctx, *chatID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, domain.ErrNotFound
+		}
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(eventTypes)
+	webhook := &domain.Webhook{
+		URL:        url,
+		ChatID:     chatID,
+		EventTypes: slices.Compact(eventTypes),
+		Secret:     secret,
+		Active:     true,
+	}
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s webhookService) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return s.webhookRepo.List(ctx)
+}
+
+func (s webhookService) GetWebhook(ctx context.Context, id uint) (*domain.Webhook, error) {
+	return s.webhookRepo.GetByID(ctx, id)
+}
+
+func (s webhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+func (s webhookService) EnableWebhook(ctx context.Context, id uint) error {
+	return s.webhookRepo.SetActive(ctx, id, true)
+}
+
+func (s webhookService) ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]domain.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, webhookID, limit)
+}
+
+func (s webhookService) GetDelivery(ctx context.Context, webhookID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	return s.webhookRepo.GetDelivery(ctx, webhookID, deliveryID)
+}
+
+func (s webhookService) Redeliver(ctx context.Context, webhookID, deliveryID uint) error {
+	webhook, err := s.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+	if !webhook.Active {
+		return domain.ErrWebhookDisabled
+	}
+
+	delivery, err := s.webhookRepo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	return s.dispatcher.Enqueue(ctx, *webhook, delivery.EventID, delivery.EventType, []byte(delivery.Payload))
+}
+
+// Publish fans the event out to every active webhook subscribed to it,
+// either globally or for the event's chat. The deliveries are stored in
+// ctx's transaction, so they are kept exactly when the event is consumed.
+func (s webhookService) Publish(ctx context.Context, event domain.Event) error {
+	subscribed, err := s.webhookRepo.FindSubscribed(ctx, event.ChatID, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range subscribed {
+		if err := s.dispatcher.Enqueue(ctx, webhook, event.ID, event.Type, payload); err != nil {
+			return fmt.Errorf("enqueueing delivery to webhook %d: %w", webhook.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GORMPlugin records a client span for every statement GORM runs, with
+// inlined literals replaced by ? so no values reach the trace backend.
+func GORMPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().Before("*").Register("tracing:before_create", p.before),
+		cb.Create().After("*").Register("tracing:after_create", p.after),
+		cb.Query().Before("*").Register("tracing:before_query", p.before),
+		cb.Query().After("*").Register("tracing:after_query", p.after),
+		cb.Update().Before("*").Register("tracing:before_update", p.before),
+		cb.Update().After("*").Register("tracing:after_update", p.after),
+		cb.Delete().Before("*").Register("tracing:before_delete", p.before),
+		cb.Delete().After("*").Register("tracing:after_delete", p.after),
+		cb.Row().Before("*").Register("tracing:before_row", p.before),
+		cb.Row().After("*").Register("tracing:after_row", p.after),
+		cb.Raw().Before("*").Register("tracing:before_raw", p.before),
+		cb.Raw().After("*").Register("tracing:after_raw", p.after),
+	)
+}
+
+func (gormPlugin) before(db *gorm.DB) {
+	if db.Statement.Context == nil {
+		return
+	}
+	_, span := tracer.Start(db.Statement.Context, "db",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL),
+	)
+	db.InstanceSet(spanKey, span)
+}
+
+func (gormPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+
+	query := SanitizeSQL(db.Statement.SQL.String())
+	operation := operationOf(query)
+	name := operation
+	if table := db.Statement.Table; table != "" {
+		name += " " + table
+		span.SetAttributes(semconv.DBCollectionName(table))
+	}
+	span.SetName(name)
+	span.SetAttributes(
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// An empty result, not a failure.
+		err = nil
+	}
+	end(span, err)
+}
+
+// literal also matches placeholders ($1), so they can be kept.
+var literal = regexp.MustCompile(`\$\d+|'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+
+// SanitizeSQL replaces the literals in query with ?, keeping placeholders.
+func SanitizeSQL(query string) string {
+	return literal.ReplaceAllStringFunc(query, func(match string) string {
+		if strings.HasPrefix(match, "$") {
+			return match
+		}
+		return "?"
+	})
+}
+
+func operationOf(query string) string {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	if operation == "" {
+		return "db"
+	}
+	return strings.ToUpper(operation)
+}
@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"chats/internal/domain"
+	"chats/internal/services"
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ChatService starts a span around every method of s.
+func ChatService(s services.ChatService) services.ChatService {
+	return &chatService{next: s}
+}
+
+// MessageService starts a span around every method of s. Message texts
+// are never recorded.
+func MessageService(s services.MessageService) services.MessageService {
+	return &messageService{next: s}
+}
+
+func chatID(id uint) attribute.KeyValue {
+	return attribute.Int64("chat.id", int64(id))
+}
+
+type chatService struct {
+	next services.ChatService
+}
+
+func (s *chatService) CreateChat(ctx context.Context, title string) (chat *domain.Chat, err error) {
+	ctx, span := tracer.Start(ctx, "ChatService.CreateChat")
+	defer func() { end(span, err) }()
+
+	chat, err = s.next.CreateChat(ctx, title)
+	if err == nil {
+		span.SetAttributes(chatID(chat.ID))
+	}
+	return chat, err
+}
+
+func (s *chatService) GetChat(ctx context.Context, id uint, limit int) (_ *domain.Chat, err error) {
+	ctx, span := tracer.Start(ctx, "ChatService.GetChat", trace.WithAttributes(chatID(id), attribute.Int("limit", limit)))
+	defer func() { end(span, err) }()
+
+	return s.next.GetChat(ctx, id, limit)
+}
+
+func (s *chatService) GetChatState(ctx context.Context, id uint) (_ *domain.Chat, err error) {
+	ctx, span := tracer.Start(ctx, "ChatService.GetChatState", trace.WithAttributes(chatID(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.GetChatState(ctx, id)
+}
+
+func (s *chatService) GetChats(ctx context.Context, ids []uint) (_ []domain.Chat, err error) {
+	ctx, span := tracer.Start(ctx, "ChatService.GetChats", trace.WithAttributes(attribute.Int("chat.count", len(ids))))
+	defer func() { end(span, err) }()
+
+	return s.next.GetChats(ctx, ids)
+}
+
+func (s *chatService) UpdateChat(ctx context.Context, id uint, title string, version uint64) (_ *domain.Chat, err error) {
+	ctx, span := tracer.Start(ctx, "ChatService.UpdateChat", trace.WithAttributes(chatID(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.UpdateChat(ctx, id, title, version)
+}
+
+func (s *chatService) DeleteChat(ctx context.Context, id uint, version uint64) (err error) {
+	ctx, span := tracer.Start(ctx, "ChatService.DeleteChat", trace.WithAttributes(chatID(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.DeleteChat(ctx, id, version)
+}
+
+func (s *chatService) ValidateChatExists(ctx context.Context, id uint) (err error) {
+	ctx, span := tracer.Start(ctx, "ChatService.ValidateChatExists", trace.WithAttributes(chatID(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.ValidateChatExists(ctx, id)
+}
+
+type messageService struct {
+	next services.MessageService
+}
+
+func (s *messageService) CreateMessage(ctx context.Context, id uint, text string) (message *domain.Message, err error) {
+	ctx, span := tracer.Start(ctx, "MessageService.CreateMessage", trace.WithAttributes(chatID(id)))
+	defer func() { end(span, err) }()
+
+	message, err = s.next.CreateMessage(ctx, id, text)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("message.seq", int64(message.Seq)))
+	}
+	return message, err
+}
+
+func (s *messageService) UpdateMessage(ctx context.Context, id uint, seq uint64, text string, version uint64) (_ *domain.Message, err error) {
+	ctx, span := tracer.Start(ctx, "MessageService.UpdateMessage",
+		trace.WithAttributes(chatID(id), attribute.Int64("message.seq", int64(seq))))
+	defer func() { end(span, err) }()
+
+	return s.next.UpdateMessage(ctx, id, seq, text, version)
+}
+
+func (s *messageService) DeleteMessage(ctx context.Context, id uint, seq uint64, version uint64) (err error) {
+	ctx, span := tracer.Start(ctx, "MessageService.DeleteMessage",
+		trace.WithAttributes(chatID(id), attribute.Int64("message.seq", int64(seq))))
+	defer func() { end(span, err) }()
+
+	return s.next.DeleteMessage(ctx, id, seq, version)
+}
+
+func (s *messageService) ListMessages(ctx context.Context, id uint, cursor domain.MessageCursor) (messages []domain.Message, err error) {
+	ctx, span := tracer.Start(ctx, "MessageService.ListMessages", trace.WithAttributes(chatID(id)))
+	defer func() { end(span, err) }()
+
+	messages, err = s.next.ListMessages(ctx, id, cursor)
+	span.SetAttributes(attribute.Int("message.count", len(messages)))
+	return messages, err
+}
+
+func (s *messageService) ListLatestMessages(ctx context.Context, ids []uint, limit int) (_ map[uint][]domain.Message, err error) {
+	ctx, span := tracer.Start(ctx, "MessageService.ListLatestMessages",
+		trace.WithAttributes(attribute.Int("chat.count", len(ids)), attribute.Int("limit", limit)))
+	defer func() { end(span, err) }()
+
+	return s.next.ListLatestMessages(ctx, ids, limit)
+}
@@ -0,0 +1,182 @@
+package tracing
+
+import (
+	"chats/internal/domain"
+	"chats/internal/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// The package tracer binds to the first global provider only, so tests
+// share one provider and swap the recorder behind it.
+var (
+	installOnce sync.Once
+	processor   = &swappableProcessor{}
+)
+
+type swappableProcessor struct {
+	mu sync.Mutex
+	sdktrace.SpanProcessor
+}
+
+func (p *swappableProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.SpanProcessor.OnStart(ctx, s)
+}
+
+func (p *swappableProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.SpanProcessor.OnEnd(s)
+}
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	processor.mu.Lock()
+	processor.SpanProcessor = recorder
+	processor.mu.Unlock()
+
+	installOnce.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	recorder := record(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/api/chats/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chats/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /api/chats/{id}", span.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := attributes(span)
+	assert.Equal(t, "/api/chats/{id}", attrs["http.route"].AsString())
+	assert.Equal(t, "/api/chats/42", attrs["url.path"].AsString())
+	assert.Equal(t, int64(500), attrs["http.response.status_code"].AsInt64())
+}
+
+type stubMessageService struct {
+	services.MessageService
+}
+
+func (stubMessageService) CreateMessage(ctx context.Context, chatID uint, text string) (*domain.Message, error) {
+	if text == "" {
+		return nil, domain.ErrEmptyText
+	}
+	return &domain.Message{ChatID: chatID, Seq: 7, Text: text}, nil
+}
+
+func TestMessageService_SpansWithoutText(t *testing.T) {
+	recorder := record(t)
+	s := MessageService(stubMessageService{})
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	_, err := s.CreateMessage(ctx, 3, "secret plans")
+	require.NoError(t, err)
+	_, err = s.CreateMessage(ctx, 3, "")
+	require.ErrorIs(t, err, domain.ErrEmptyText)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	created, rejected := spans[0], spans[1]
+
+	assert.Equal(t, "MessageService.CreateMessage", created.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), created.Parent().SpanID())
+	attrs := attributes(created)
+	assert.Equal(t, int64(3), attrs["chat.id"].AsInt64())
+	assert.Equal(t, int64(7), attrs["message.seq"].AsInt64())
+	for _, kv := range created.Attributes() {
+		assert.NotContains(t, kv.Value.Emit(), "secret")
+	}
+
+	assert.Equal(t, codes.Error, rejected.Status().Code)
+	assert.Equal(t, domain.ErrEmptyText.Error(), rejected.Status().Description)
+}
+
+func TestGORMPlugin_RecordsSanitizedStatements(t *testing.T) {
+	recorder := record(t)
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GORMPlugin()))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	db.WithContext(ctx).Where("title = ?", "secret").First(&domain.Chat{})
+	db.WithContext(ctx).Exec("UPDATE chats SET title = 'secret' WHERE id = 5")
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	query := spans[0]
+	assert.Equal(t, "SELECT chats", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	attrs := attributes(query)
+	assert.Equal(t, "postgresql", attrs["db.system"].AsString())
+	assert.Equal(t, "chats", attrs["db.collection.name"].AsString())
+	assert.Equal(t, `SELECT * FROM "chats" WHERE title = $1 ORDER BY "chats"."id" LIMIT $2`, attrs["db.query.text"].AsString())
+
+	exec := spans[1]
+	assert.Equal(t, "UPDATE", exec.Name())
+	assert.Equal(t, "UPDATE chats SET title = ? WHERE id = ?", attributes(exec)["db.query.text"].AsString())
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT * FROM "messages" WHERE chat_id = $1 AND seq > $2`, `SELECT * FROM "messages" WHERE chat_id = $1 AND seq > $2`},
+		{`INSERT INTO messages (text) VALUES ('it''s secret')`, `INSERT INTO messages (text) VALUES (?)`},
+		{`SELECT * FROM chats WHERE id IN (1,2,3) LIMIT 20`, `SELECT * FROM chats WHERE id IN (?,?,?) LIMIT ?`},
+		{`SELECT * FROM table1 WHERE score > 1.5`, `SELECT * FROM table1 WHERE score > ?`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SanitizeSQL(tt.query))
+	}
+}
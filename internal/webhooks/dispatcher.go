@@ -0,0 +1,220 @@
+package webhooks
+
+import (
+	"bytes"
+	"chats/internal/config"
+	"chats/internal/domain"
+	"chats/internal/helpers"
+	"chats/internal/repositories"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	maxStoredBodyLength = 4096
+
+	// claimMargin is added to the attempt timeout for the time a claimed
+	// job stays hidden from other workers, covering storing the result.
+	claimMargin = time.Minute
+)
+
+// Dispatcher delivers events to webhook endpoints from a pool of workers.
+// Deliveries are stored as jobs, so they survive restarts; failed attempts
+// are rescheduled with exponential backoff and jitter until MaxAttempts is
+// reached. Every attempt is stored as a WebhookDelivery.
+type Dispatcher struct {
+	repo   repositories.WebhookRepository
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+func NewDispatcher(repo repositories.WebhookRepository, cfg config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		cfg:    cfg,
+		client: newHTTPClient(cfg.Timeout, cfg.AllowPrivateNetworks),
+	}
+}
+
+// Run starts the workers and blocks until ctx is cancelled and in-flight
+// attempts have finished. Jobs left behind are picked up on the next start.
+func (d *Dispatcher) Run(ctx context.Context) {
+	workers := max(d.cfg.Workers, 1)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	poll := time.NewTicker(d.cfg.PollInterval)
+	defer poll.Stop()
+
+	for ctx.Err() == nil {
+		jobs, err := d.repo.ClaimJobs(ctx, 1, d.cfg.Timeout+claimMargin)
+		if err != nil && ctx.Err() == nil {
+			slog.Default().Error("Error claiming webhook jobs", "error", err)
+		}
+		for _, j := range jobs {
+			d.process(ctx, j)
+		}
+		if len(jobs) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+	}
+}
+
+func (d *Dispatcher) ValidateTarget(ctx context.Context, rawURL string) error {
+	return ValidateURL(ctx, rawURL, d.cfg.AllowPrivateNetworks)
+}
+
+// Enqueue stores a delivery of the event to webhook. Run within the
+// transaction that consumes the event, the delivery is kept exactly when
+// the event is.
+func (d *Dispatcher) Enqueue(ctx context.Context, webhook domain.Webhook, eventID string, eventType domain.EventType, payload []byte) error {
+	return d.repo.CreateJob(ctx, &domain.WebhookJob{
+		WebhookID: webhook.ID,
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   string(payload),
+		Attempt:   1,
+	})
+}
+
+func (d *Dispatcher) process(ctx context.Context, j domain.WebhookJob) {
+	logger := slog.Default().With("webhook_id", j.WebhookID, "event_id", j.EventID, "attempt", j.Attempt)
+	store := context.WithoutCancel(ctx)
+
+	// Reloaded for every attempt, so retries follow changes to the webhook.
+	webhook, err := d.repo.GetByID(ctx, j.WebhookID)
+	if errors.Is(err, domain.ErrNotFound) || err == nil && !webhook.Active {
+		logger.Info("Webhook deleted or disabled, dropping delivery")
+		d.finish(store, logger, j)
+		return
+	}
+	if err != nil {
+		logger.Error("Error loading webhook", "error", err)
+		return
+	}
+
+	delivery := d.send(ctx, webhook, j)
+	if ctx.Err() != nil {
+		// Cut off by shutdown: not the endpoint's failure, so the attempt is
+		// made again on the next start.
+		if err := d.repo.RescheduleJob(store, j.ID, j.Attempt, time.Now()); err != nil {
+			logger.Error("Error releasing webhook job", "error", err)
+		}
+		return
+	}
+
+	if err := d.repo.CreateDelivery(store, delivery); err != nil {
+		logger.Error("Error storing webhook delivery", "error", err)
+	}
+
+	if delivery.Status == domain.DeliverySucceeded {
+		if err := d.repo.RecordSuccess(store, webhook.ID); err != nil {
+			logger.Error("Error resetting webhook failures", "error", err)
+		}
+		d.finish(store, logger, j)
+		return
+	}
+
+	logger.Warn("Webhook delivery failed", "status", delivery.ResponseStatus, "error", delivery.Error)
+
+	disabled, err := d.repo.RecordFailure(store, webhook.ID, d.cfg.DisableAfterFailures)
+	if err != nil {
+		logger.Error("Error recording webhook failure", "error", err)
+	}
+	if disabled {
+		logger.Warn("Webhook disabled after repeated failures")
+		d.finish(store, logger, j)
+		return
+	}
+
+	if j.Attempt >= d.cfg.MaxAttempts {
+		logger.Warn("Webhook delivery gave up")
+		d.finish(store, logger, j)
+		return
+	}
+
+	retryAt := time.Now().Add(helpers.Backoff(j.Attempt, d.cfg.BaseBackoff, d.cfg.MaxBackoff))
+	if err := d.repo.RescheduleJob(store, j.ID, j.Attempt+1, retryAt); err != nil {
+		logger.Error("Error rescheduling webhook delivery", "error", err)
+	}
+}
+
+// finish removes a job that needs no further attempts. If that fails, the
+// job is claimed again once its lease runs out.
+func (d *Dispatcher) finish(ctx context.Context, logger *slog.Logger, j domain.WebhookJob) {
+	if err := d.repo.DeleteJob(ctx, j.ID); err != nil {
+		logger.Error("Error removing webhook job", "error", err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook *domain.Webhook, j domain.WebhookJob) *domain.WebhookDelivery {
+	delivery := &domain.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   j.EventID,
+		EventType: j.EventType,
+		Payload:   j.Payload,
+		Attempt:   j.Attempt,
+		Status:    domain.DeliveryFailed,
+	}
+
+	payload := []byte(j.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chats-webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(j.EventType))
+	req.Header.Set(HeaderDelivery, j.EventID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, payload))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxStoredBodyLength))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = string(body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Status = domain.DeliverySucceeded
+	}
+
+	return delivery
+}
@@ -0,0 +1,431 @@
+package webhooks
+
+import (
+	"chats/internal/config"
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhookRepository struct {
+	mu         sync.Mutex
+	webhooks   map[uint]domain.Webhook
+	deliveries []domain.WebhookDelivery
+	jobs       []domain.WebhookJob
+	failures   int
+	disabled   bool
+}
+
+func newFakeWebhookRepository(webhooks ...domain.Webhook) *fakeWebhookRepository {
+	f := &fakeWebhookRepository{webhooks: map[uint]domain.Webhook{}}
+	for _, webhook := range webhooks {
+		webhook.Active = true
+		f.webhooks[webhook.ID] = webhook
+	}
+	return f
+}
+
+func (f *fakeWebhookRepository) Create(context.Context, *domain.Webhook) error { return nil }
+
+func (f *fakeWebhookRepository) GetByID(_ context.Context, id uint) (*domain.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	webhook, ok := f.webhooks[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &webhook, nil
+}
+
+func (f *fakeWebhookRepository) List(context.Context) ([]domain.Webhook, error) { return nil, nil }
+
+func (f *fakeWebhookRepository) Delete(_ context.Context, id uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.webhooks, id)
+	return nil
+}
+
+func (f *fakeWebhookRepository) update(id uint, fn func(*domain.Webhook)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	webhook := f.webhooks[id]
+	fn(&webhook)
+	f.webhooks[id] = webhook
+}
+
+func (f *fakeWebhookRepository) SetActive(context.Context, uint, bool) error { return nil }
+func (f *fakeWebhookRepository) FindSubscribed(context.Context, uint, domain.EventType) ([]domain.Webhook, error) {
+	return nil, nil
+}
+
+func (f *fakeWebhookRepository) RecordSuccess(context.Context, uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = 0
+	return nil
+}
+
+func (f *fakeWebhookRepository) RecordFailure(_ context.Context, id uint, disableAfter int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures++
+	if f.failures >= disableAfter {
+		f.disabled = true
+		webhook := f.webhooks[id]
+		webhook.Active = false
+		f.webhooks[id] = webhook
+	}
+	return f.disabled, nil
+}
+
+func (f *fakeWebhookRepository) CreateDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries = append(f.deliveries, *delivery)
+	return nil
+}
+
+func (f *fakeWebhookRepository) ListDeliveries(context.Context, uint, int) ([]domain.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (f *fakeWebhookRepository) GetDelivery(context.Context, uint, uint) (*domain.WebhookDelivery, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeWebhookRepository) CreateJob(_ context.Context, job *domain.WebhookJob) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.ID = uint64(len(f.jobs) + 1)
+	job.AvailableAt = time.Now()
+	f.jobs = append(f.jobs, *job)
+	return nil
+}
+
+func (f *fakeWebhookRepository) ClaimJobs(_ context.Context, limit int, lease time.Duration) ([]domain.WebhookJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []domain.WebhookJob
+	for i, job := range f.jobs {
+		if job.ID == 0 || job.AvailableAt.After(time.Now()) || len(claimed) == limit {
+			continue
+		}
+		f.jobs[i].AvailableAt = time.Now().Add(lease)
+		claimed = append(claimed, f.jobs[i])
+	}
+	return claimed, nil
+}
+
+func (f *fakeWebhookRepository) RescheduleJob(_ context.Context, id uint64, attempt int, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs[id-1].Attempt = attempt
+	f.jobs[id-1].AvailableAt = at
+	return nil
+}
+
+// DeleteJob keeps the slot, zeroing its ID, so IDs stay indexes.
+func (f *fakeWebhookRepository) DeleteJob(_ context.Context, id uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs[id-1].ID = 0
+	return nil
+}
+
+func (f *fakeWebhookRepository) pendingJobs() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, job := range f.jobs {
+		if job.ID != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeWebhookRepository) snapshot() []domain.WebhookDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]domain.WebhookDelivery(nil), f.deliveries...)
+}
+
+func testConfig() config.WebhookConfig {
+	return config.WebhookConfig{
+		Workers:              2,
+		PollInterval:         5 * time.Millisecond,
+		Timeout:              time.Second,
+		MaxAttempts:          4,
+		BaseBackoff:          time.Millisecond,
+		MaxBackoff:           5 * time.Millisecond,
+		DisableAfterFailures: 10,
+		AllowPrivateNetworks: true,
+	}
+}
+
+func startDispatcher(t *testing.T, repo *fakeWebhookRepository, cfg config.WebhookConfig) *Dispatcher {
+	t.Helper()
+
+	d, stop := runDispatcher(repo, cfg)
+	t.Cleanup(stop)
+	return d
+}
+
+// runDispatcher starts a dispatcher; stop cancels it and waits for Run.
+func runDispatcher(repo *fakeWebhookRepository, cfg config.WebhookConfig) (*Dispatcher, func()) {
+	d := NewDispatcher(repo, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	return d, func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	const secret = "s3cret"
+	payload := []byte(`{"id":"evt-1","type":"message.created"}`)
+
+	received := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, payload, body)
+		assert.True(t, Verify(secret, r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)))
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	webhook := domain.Webhook{ID: 1, URL: receiver.URL, Secret: secret}
+	repo := newFakeWebhookRepository(webhook)
+	d := startDispatcher(t, repo, testConfig())
+
+	require.NoError(t, d.Enqueue(context.Background(), webhook, "evt-1", domain.EventMessageCreated, payload))
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "message.created", r.Header.Get(HeaderEvent))
+		assert.Equal(t, "evt-1", r.Header.Get(HeaderDelivery))
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	require.Eventually(t, func() bool { return len(repo.snapshot()) == 1 }, time.Second, 5*time.Millisecond)
+	delivery := repo.snapshot()[0]
+	assert.Equal(t, domain.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, http.StatusNoContent, delivery.ResponseStatus)
+	assert.Equal(t, 1, delivery.Attempt)
+}
+
+func TestDispatcher_RetriesUntilSuccess(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	webhook := domain.Webhook{ID: 1, URL: receiver.URL, Secret: "x"}
+	repo := newFakeWebhookRepository(webhook)
+	d := startDispatcher(t, repo, testConfig())
+
+	require.NoError(t, d.Enqueue(context.Background(), webhook, "evt-2", domain.EventChatCreated, []byte(`{}`)))
+
+	require.Eventually(t, func() bool { return len(repo.snapshot()) == 3 }, 2*time.Second, 5*time.Millisecond)
+	deliveries := repo.snapshot()
+	assert.Equal(t, domain.DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].ResponseStatus)
+	assert.Equal(t, 3, deliveries[2].Attempt)
+	assert.Equal(t, domain.DeliverySucceeded, deliveries[2].Status)
+}
+
+func TestDispatcher_StopsRetryingWhenDisabled(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	cfg := testConfig()
+	cfg.MaxAttempts = 10
+	cfg.DisableAfterFailures = 2
+
+	webhook := domain.Webhook{ID: 1, URL: receiver.URL, Secret: "x"}
+	repo := newFakeWebhookRepository(webhook)
+	d := startDispatcher(t, repo, cfg)
+
+	require.NoError(t, d.Enqueue(context.Background(), webhook, "evt-3", domain.EventChatCreated, []byte(`{}`)))
+
+	require.Eventually(t, func() bool { return len(repo.snapshot()) == 2 }, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, repo.snapshot(), 2)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestDispatcher_DeliversQueuedJobsAfterRestart(t *testing.T) {
+	var up atomic.Bool
+	inFlight := make(chan struct{}, 1)
+	delivered := make(chan string, 3)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		if !up.Load() {
+			select {
+			case inFlight <- struct{}{}:
+			default:
+			}
+			<-r.Context().Done()
+			return
+		}
+		delivered <- r.Header.Get(HeaderDelivery)
+	}))
+	defer receiver.Close()
+
+	webhook := domain.Webhook{ID: 1, URL: receiver.URL, Secret: "x"}
+	repo := newFakeWebhookRepository(webhook)
+	cfg := testConfig()
+	cfg.Workers = 1
+
+	d, stop := runDispatcher(repo, cfg)
+	for _, id := range []string{"evt-1", "evt-2", "evt-3"} {
+		require.NoError(t, d.Enqueue(context.Background(), webhook, id, domain.EventChatCreated, []byte(`{}`)))
+	}
+	<-inFlight
+	stop()
+	assert.Equal(t, 3, repo.pendingJobs(), "stopping keeps the queued jobs")
+	assert.Empty(t, repo.snapshot(), "the interrupted attempt is not a failed delivery")
+	assert.Zero(t, repo.failures)
+
+	up.Store(true)
+	startDispatcher(t, repo, cfg)
+
+	var got []string
+	for range 3 {
+		select {
+		case id := <-delivered:
+			got = append(got, id)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("delivered %v after the restart", got)
+		}
+	}
+	assert.ElementsMatch(t, []string{"evt-1", "evt-2", "evt-3"}, got)
+	require.Eventually(t, func() bool { return repo.pendingJobs() == 0 }, time.Second, 5*time.Millisecond)
+	for _, delivery := range repo.snapshot() {
+		assert.Equal(t, 1, delivery.Attempt)
+	}
+}
+
+func TestDispatcher_DropsJobsOfDeletedOrDisabledWebhooks(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	deleted := domain.Webhook{ID: 1, URL: receiver.URL, Secret: "x"}
+	disabled := domain.Webhook{ID: 2, URL: receiver.URL, Secret: "x"}
+	repo := newFakeWebhookRepository(deleted, disabled)
+	d := NewDispatcher(repo, testConfig())
+	require.NoError(t, d.Enqueue(context.Background(), deleted, "evt-5", domain.EventChatCreated, []byte(`{}`)))
+	require.NoError(t, d.Enqueue(context.Background(), disabled, "evt-5", domain.EventChatCreated, []byte(`{}`)))
+	require.NoError(t, repo.Delete(context.Background(), deleted.ID))
+	repo.update(disabled.ID, func(w *domain.Webhook) { w.Active = false })
+
+	startDispatcher(t, repo, testConfig())
+
+	require.Eventually(t, func() bool { return repo.pendingJobs() == 0 }, time.Second, 5*time.Millisecond)
+	assert.Zero(t, calls.Load())
+	assert.Empty(t, repo.snapshot())
+}
+
+func TestDispatcher_RetriesWithCurrentWebhook(t *testing.T) {
+	old := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer old.Close()
+
+	const secret = "rotated"
+	received := make(chan bool, 1)
+	current := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- Verify(secret, r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature))
+	}))
+	defer current.Close()
+
+	cfg := testConfig()
+	cfg.BaseBackoff = 100 * time.Millisecond
+	cfg.MaxBackoff = 100 * time.Millisecond
+
+	webhook := domain.Webhook{ID: 1, URL: old.URL, Secret: "x"}
+	repo := newFakeWebhookRepository(webhook)
+	d := startDispatcher(t, repo, cfg)
+	require.NoError(t, d.Enqueue(context.Background(), webhook, "evt-6", domain.EventChatCreated, []byte(`{}`)))
+
+	require.Eventually(t, func() bool { return len(repo.snapshot()) == 1 }, time.Second, 5*time.Millisecond)
+	repo.update(webhook.ID, func(w *domain.Webhook) {
+		w.URL = current.URL
+		w.Secret = secret
+	})
+
+	select {
+	case verified := <-received:
+		assert.True(t, verified, "the retry is signed with the new secret")
+	case <-time.After(2 * time.Second):
+		t.Fatal("the retry did not go to the new URL")
+	}
+}
+
+func TestDispatcher_BlocksLoopbackByDefault(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	err := ValidateURL(context.Background(), receiver.URL, false)
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	assert.NoError(t, ValidateURL(context.Background(), receiver.URL, true))
+
+	cfg := testConfig()
+	cfg.AllowPrivateNetworks = false
+	cfg.MaxAttempts = 1
+
+	// Simulates a host that resolved publicly at registration and later rebinds to loopback.
+	webhook := domain.Webhook{ID: 1, URL: receiver.URL, Secret: "x"}
+	repo := newFakeWebhookRepository(webhook)
+	d := startDispatcher(t, repo, cfg)
+
+	require.NoError(t, d.Enqueue(context.Background(), webhook, "evt-4", domain.EventChatCreated, []byte(`{}`)))
+
+	require.Eventually(t, func() bool { return len(repo.snapshot()) == 1 }, 2*time.Second, 5*time.Millisecond)
+	delivery := repo.snapshot()[0]
+	assert.Equal(t, domain.DeliveryFailed, delivery.Status)
+	assert.Contains(t, delivery.Error, ErrBlockedAddress.Error())
+	assert.Zero(t, calls.Load())
+}
+
+func TestValidateURL_RejectsNonHTTP(t *testing.T) {
+	for _, raw := range []string{"", "ftp://example.com", "/relative", "http://"} {
+		err := ValidateURL(context.Background(), raw, true)
+		assert.True(t, errors.Is(err, domain.ErrInvalidInput), raw)
+	}
+}
@@ -0,0 +1,84 @@
+package webhooks
+
+import (
+	"chats/internal/domain"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var ErrBlockedAddress = errors.New("webhook target resolves to a private or loopback address")
+
+var carrierGradeNAT = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!carrierGradeNAT.Contains(addr)
+}
+
+// ValidateURL rejects non-HTTP targets and, unless allowPrivate is set, hosts
+// that resolve to non-public addresses. The dialer re-checks the address at
+// connect time, so DNS rebinding after registration is still blocked.
+func ValidateURL(ctx context.Context, rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return domain.NewError(domain.ErrInvalidInput, "webhook url must be an absolute http(s) url")
+	}
+
+	if allowPrivate {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return domain.NewError(domain.ErrInvalidInput, "cannot resolve webhook host")
+	}
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return &domain.Error{Kind: domain.ErrInvalidInput, Message: ErrBlockedAddress.Error(), Err: ErrBlockedAddress}
+		}
+	}
+
+	return nil
+}
+
+func newHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublic(addrPort.Addr()) {
+				return ErrBlockedAddress
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}